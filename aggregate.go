@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// UsageEvent is one timestamped usage contribution read from a session file.
+type UsageEvent struct {
+	Time    time.Time // zero if the entry had no timestamp
+	ID      string    // dedup key across session files; empty if none
+	Key     UsageKey
+	Tokens  TokenStats
+	Session SessionRef
+}
+
+// SessionRef identifies the session file an event was read from.
+type SessionRef struct {
+	Provider string
+	ID       string
+	Path     string
+	Start    time.Time // first entry in the file
+}
+
+// Window is a time range [Since, Until) to aggregate usage over. A zero
+// Until means no upper bound.
+type Window struct {
+	Since time.Time
+	Until time.Time
+}
+
+// Contains reports whether t falls in the window. Events without a
+// timestamp only count toward windows without an upper bound.
+func (w Window) Contains(t time.Time) bool {
+	if t.IsZero() {
+		return w.Until.IsZero()
+	}
+	return inWindow(t, w.Since, w.Until)
+}
+
+// aggKind selects what an aggregation window keeps.
+type aggKind int
+
+const (
+	kindTotals   aggKind = iota // TokenBreakdown per provider
+	kindDaily                   // totals plus a per-day breakdown
+	kindSessions                // totals plus per-session usage
+)
+
+type aggWindow struct {
+	Window
+	kind     aggKind
+	tokens   map[string]TokenBreakdown  // by provider
+	days     map[string]DailyTokenStats // by provider, kindDaily only
+	sessions map[string]*sessionTotals  // by path, kindSessions only
+}
+
+type sessionTotals struct {
+	ref    SessionRef
+	end    time.Time
+	tokens TokenBreakdown
+}
+
+// Aggregator buckets the events of a single scan into any number of named
+// windows, per provider, so one pass over the session files can answer
+// today, the last 7 days and the month at once.
+type Aggregator struct {
+	windows map[string]*aggWindow
+	order   []string
+	events  map[string][]UsageEvent // by session path, for Replace
+
+	owners  map[string]string // event ID -> session path it is counted for
+	dropped map[string]int    // duplicate events dropped, by session path
+}
+
+func newAggregator() *Aggregator {
+	return &Aggregator{
+		windows: make(map[string]*aggWindow),
+		events:  make(map[string][]UsageEvent),
+		owners:  make(map[string]string),
+		dropped: make(map[string]int),
+	}
+}
+
+// Window adds a window that keeps token totals.
+func (a *Aggregator) Window(name string, w Window) {
+	a.add(name, w, kindTotals)
+}
+
+// DailyWindow adds a window that also buckets usage by day of month.
+func (a *Aggregator) DailyWindow(name string, w Window) {
+	a.add(name, w, kindDaily)
+}
+
+// SessionWindow adds a window that also keeps per-session usage.
+func (a *Aggregator) SessionWindow(name string, w Window) {
+	a.add(name, w, kindSessions)
+}
+
+func (a *Aggregator) add(name string, w Window, kind aggKind) {
+	if _, ok := a.windows[name]; !ok {
+		a.order = append(a.order, name)
+	}
+	a.windows[name] = &aggWindow{
+		Window:   w,
+		kind:     kind,
+		tokens:   make(map[string]TokenBreakdown),
+		days:     make(map[string]DailyTokenStats),
+		sessions: make(map[string]*sessionTotals),
+	}
+}
+
+// Since returns the earliest start of any window: the point a scan has to
+// reach back to.
+func (a *Aggregator) Since() time.Time {
+	var since time.Time
+	for i, name := range a.order {
+		w := a.windows[name]
+		if i == 0 || w.Since.Before(since) {
+			since = w.Since
+		}
+	}
+	return since
+}
+
+// Add records an event in every window that contains it. An event whose
+// ID was already added from another session file is a copy, e.g. from a
+// resumed or forked Claude session, and is dropped.
+func (a *Aggregator) Add(e UsageEvent) {
+	path := e.Session.Path
+	if e.ID != "" {
+		if owner, ok := a.owners[e.ID]; ok && owner != path {
+			a.dropped[path]++
+			return
+		}
+		a.owners[e.ID] = path
+	}
+	a.events[path] = append(a.events[path], e)
+	provider := e.Session.Provider
+	for _, name := range a.order {
+		w := a.windows[name]
+		if !w.Contains(e.Time) {
+			continue
+		}
+		if w.tokens[provider] == nil {
+			w.tokens[provider] = make(TokenBreakdown)
+		}
+		w.tokens[provider].Add(e.Key, e.Tokens)
+
+		switch w.kind {
+		case kindDaily:
+			if e.Time.IsZero() {
+				continue
+			}
+			if w.days[provider] == nil {
+				w.days[provider] = make(DailyTokenStats)
+			}
+			w.days[provider].Add(e.Time.Day(), e.Key.Model, e.Tokens)
+		case kindSessions:
+			st := w.sessions[e.Session.Path]
+			if st == nil {
+				st = &sessionTotals{ref: e.Session, tokens: make(TokenBreakdown)}
+				w.sessions[e.Session.Path] = st
+			}
+			st.tokens.Add(e.Key, e.Tokens)
+			if e.Time.After(st.end) {
+				st.end = e.Time
+			}
+		}
+	}
+}
+
+// Replace swaps the events previously added from the session file at path
+// for events, so a file that grew can be rescanned on its own.
+func (a *Aggregator) Replace(path string, events []UsageEvent) {
+	for _, e := range a.events[path] {
+		if e.ID != "" && a.owners[e.ID] == path {
+			delete(a.owners, e.ID)
+		}
+		provider := e.Session.Provider
+		for _, w := range a.windows {
+			if !w.Contains(e.Time) {
+				continue
+			}
+			if b := w.tokens[provider]; b != nil {
+				b.Sub(e.Key, e.Tokens)
+			}
+			if d := w.days[provider]; d != nil && !e.Time.IsZero() {
+				d.Sub(e.Time.Day(), e.Key.Model, e.Tokens)
+			}
+		}
+	}
+	delete(a.events, path)
+	delete(a.dropped, path)
+	for _, w := range a.windows {
+		delete(w.sessions, path)
+	}
+	for _, e := range events {
+		a.Add(e)
+	}
+}
+
+// Duplicates returns how many events were dropped as copies of events
+// already counted from another session file.
+func (a *Aggregator) Duplicates() int {
+	n := 0
+	for _, d := range a.dropped {
+		n += d
+	}
+	return n
+}
+
+// Tokens returns the usage of the given providers in the named window.
+func (a *Aggregator) Tokens(name string, ps []Provider) TokenBreakdown {
+	out := make(TokenBreakdown)
+	if w := a.windows[name]; w != nil {
+		for _, p := range ps {
+			out.Merge(w.tokens[p.Name()])
+		}
+	}
+	return out
+}
+
+// Daily returns the usage of the given providers in the named daily
+// window, by day of month.
+func (a *Aggregator) Daily(name string, ps []Provider) DailyTokenStats {
+	out := make(DailyTokenStats)
+	if w := a.windows[name]; w != nil {
+		for _, p := range ps {
+			out.Merge(w.days[p.Name()])
+		}
+	}
+	return out
+}
+
+// Sessions returns the sessions of the given providers with usage in the
+// named session window.
+func (a *Aggregator) Sessions(name string, ps []Provider) []Session {
+	sessions := []Session{}
+	w := a.windows[name]
+	if w == nil {
+		return sessions
+	}
+	for _, st := range w.sessions {
+		for _, p := range ps {
+			if p.Name() == st.ref.Provider {
+				sessions = append(sessions, newSession(st.ref.Provider, st.ref.ID, st.ref.Path, st.ref.Start, st.end, st.tokens))
+				break
+			}
+		}
+	}
+	return sessions
+}
+
+// scanUsage scans the given providers concurrently, far enough back to
+// cover every window of agg. A provider that fails doesn't stop the
+// others; their errors are joined.
+func scanUsage(ctx context.Context, ps []Provider, agg *Aggregator) error {
+	since := agg.Since()
+	var (
+		mu   sync.Mutex // serializes agg.Add across providers
+		wg   sync.WaitGroup
+		errs = make([]error, len(ps))
+	)
+	for i, p := range ps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = p.ScanUsage(ctx, since, func(e UsageEvent) {
+				mu.Lock()
+				defer mu.Unlock()
+				agg.Add(e)
+			})
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
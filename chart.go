@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// chartDays is how many days the chart view shows, today included.
+const chartDays = 30
+
+// sparkWidth is the width of the utilization sparkline next to each bar.
+const sparkWidth = 10
+
+// historyLoadedMsg carries the samples read from the history file.
+type historyLoadedMsg struct {
+	samples []historySample
+}
+
+// loadHistoryCmd reads the last week of recorded utilization, enough for
+// the sparklines of every window.
+func loadHistoryCmd() tea.Cmd {
+	return func() tea.Msg {
+		samples, _ := readHistory(time.Now().AddDate(0, 0, -7))
+		return historyLoadedMsg{samples: samples}
+	}
+}
+
+// addHistory appends the samples of a fetch to the in-memory history,
+// unless they are already there.
+func (m *model) addHistory(provider string, limits *RateLimits) {
+	for _, s := range newHistorySamples(provider, limits) {
+		if !m.hasSample(s) {
+			m.history = append(m.history, s)
+		}
+	}
+}
+
+// hasSample reports whether s is in the history, looking back from the
+// most recent sample of its window.
+func (m model) hasSample(s historySample) bool {
+	for i := len(m.history) - 1; i >= 0; i-- {
+		if h := m.history[i]; h.Provider == s.Provider && h.Window == s.Window {
+			return h.key() == s.key()
+		}
+	}
+	return false
+}
+
+// chartWindow returns the name of the aggregation window holding the
+// usage of the day starting at day.
+func chartWindow(day time.Time) string {
+	return "chart:" + day.Format("2006-01-02")
+}
+
+// lastDays returns the start of each of the n days up to and including
+// the one holding now, oldest first.
+func lastDays(now time.Time, n int) []time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	days := make([]time.Time, n)
+	for i := range days {
+		days[i] = today.AddDate(0, 0, i-n+1)
+	}
+	return days
+}
+
+// sparkBlocks are the eighth-height blocks used by the sparklines and the
+// chart, lowest first.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// renderSparkline renders the recorded utilization of a window over its
+// length, or the last day if the length is unknown, as sparkWidth columns
+// of the percent used. Columns before the first sample are blank; later
+// columns without a sample repeat the one before. It is empty when there
+// are fewer than two samples.
+func (m model) renderSparkline(provider string, w LimitWindow, now time.Time) string {
+	span := w.Length
+	if span <= 0 {
+		span = 24 * time.Hour
+	}
+	start := now.Add(-span)
+
+	var cols [sparkWidth]float64
+	var have [sparkWidth]bool
+	n := 0
+	for _, s := range m.history {
+		if s.Provider != provider || s.Window != w.Key || s.Time.Before(start) || s.Time.After(now) {
+			continue
+		}
+		i := min(sparkWidth-1, int(float64(sparkWidth)*float64(s.Time.Sub(start))/float64(span)))
+		cols[i], have[i] = s.UsedPercent, true
+		n++
+	}
+	if n < 2 {
+		return ""
+	}
+
+	var b strings.Builder
+	started := false
+	last := 0.0
+	for i := range cols {
+		if have[i] {
+			started, last = true, cols[i]
+		}
+		if !started {
+			b.WriteByte(' ')
+			continue
+		}
+		level := int(max(0, min(last, 100)) / 100 * float64(len(sparkBlocks)-1))
+		b.WriteRune(sparkBlocks[level])
+	}
+	return lipgloss.NewStyle().Foreground(resetColor).Render(b.String())
+}
+
+// showSparklines reports whether the bars leave room for sparklines.
+func (m model) showSparklines() bool {
+	return !m.narrow() && m.contentWidth()-m.labelWidth()-7-(sparkWidth+1) >= 20
+}
+
+// renderChart renders the daily token totals of the last chartDays days
+// as a bar chart, with the values of the day under the cursor.
+func (m model) renderChart() string {
+	const height = 8
+
+	var b strings.Builder
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("243"))
+	valStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+	selStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("99"))
+
+	b.WriteString(sectionStyle.Render(fmt.Sprintf("Daily tokens (%dd)", chartDays)) + "\n")
+	if m.chartDays == nil {
+		b.WriteString("  loading...\n")
+		return b.String()
+	}
+
+	enabled := enabledProviders(m.config)
+	days := make([]ModelTokenStats, len(m.chartDays))
+	peak := 0
+	for i, day := range m.chartDays {
+		days[i] = m.usage.Tokens(chartWindow(day), enabled).ByModel()
+		peak = max(peak, days[i].Sum().Total())
+	}
+	if peak == 0 {
+		b.WriteString(dimStyle.Render("  no usage") + "\n")
+		b.WriteString(footerStyle.Render("  [t] back") + "\n")
+		return b.String()
+	}
+
+	// a column per day, with a gap between columns when there's room
+	colWidth := max(1, min(3, (m.contentWidth()-2)/len(days)))
+	barWidth := max(1, colWidth-1)
+
+	b.WriteString(dimStyle.Render("  peak "+formatTokenCount(peak)) + "\n")
+	for row := height - 1; row >= 0; row-- {
+		b.WriteString("  ")
+		for i, models := range days {
+			total := models.Sum().Total()
+			eighths := total * height * len(sparkBlocks) / peak
+			if total > 0 {
+				eighths = max(1, eighths) // any usage shows
+			}
+			cell := " "
+			if fill := min(eighths-row*len(sparkBlocks), len(sparkBlocks)); fill > 0 {
+				cell = string(sparkBlocks[fill-1])
+			}
+			cell = strings.Repeat(cell, barWidth) + strings.Repeat(" ", colWidth-barWidth)
+			if i == m.chartCursor {
+				b.WriteString(selStyle.Render(cell))
+			} else {
+				b.WriteString(valStyle.Render(cell))
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	// axis: first and last day at either end
+	width := colWidth * len(days)
+	first := m.chartDays[0].Format("Jan 2")
+	last := m.chartDays[len(m.chartDays)-1].Format("Jan 2")
+	b.WriteString(dimStyle.Render("  "+first+strings.Repeat(" ", max(1, width-len(first)-len(last)))+last) + "\n")
+
+	// the exact counts of the day under the cursor; input includes cache
+	// reads and writes, as in the report
+	models := days[m.chartCursor]
+	s := models.Sum()
+	day := fmt.Sprintf("  %s  %d tokens", m.chartDays[m.chartCursor].Format("Mon Jan 2"), s.Total())
+	counts := fmt.Sprintf("  in %d  out %d  cache r/w %d/%d",
+		s.InputTokens+s.CacheCreation+s.CacheRead, s.OutputTokens, s.CacheRead, s.CacheCreation)
+	if s.Reasoning > 0 {
+		counts += fmt.Sprintf("  rsn %d", s.Reasoning)
+	}
+	b.WriteString("\n" + selStyle.Render(day) + m.renderCost(models) + "\n")
+	b.WriteString(valStyle.Render(counts) + "\n")
+
+	b.WriteString(footerStyle.Render("  [←/→] move  [t] back") + "\n")
+	return b.String()
+}
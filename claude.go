@@ -1,17 +1,202 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"path/filepath"
+	"sync"
 	"time"
 )
 
 var httpClient = &http.Client{Timeout: 10 * time.Second}
 
-func fetchUsage(token string) (*UsageResponse, error) {
-	req, err := http.NewRequest("GET", "https://api.anthropic.com/api/oauth/usage", nil)
+func init() {
+	registerProvider(&claudeProvider{})
+}
+
+// claudeProvider reports rate limits from the Claude OAuth usage API and
+// token counts from Claude Code's local session files.
+type claudeProvider struct {
+	// profile is the extra account this provider tracks; the zero profile
+	// is the default account.
+	profile  ClaudeProfile
+	tokenURL string // OAuth token endpoint; empty for Anthropic's
+
+	mu    sync.Mutex
+	creds OAuthEntry
+
+	refreshMu sync.Mutex // serializes token refreshes
+}
+
+func (p *claudeProvider) Name() string {
+	if p.profile.Name == "" {
+		return "claude"
+	}
+	return "claude-" + p.profile.Name
+}
+
+func (p *claudeProvider) Title() string {
+	if p.profile.Name == "" {
+		return "Claude"
+	}
+	return "Claude (" + p.profile.Name + ")"
+}
+
+func (p *claudeProvider) Configure(cfg Config) {
+	p.profile.CredentialsCommand = cfg.Claude.CredentialsCommand
+	p.tokenURL = cfg.Claude.TokenURL
+}
+
+// Profiles returns a provider for each profile in cfg.
+func (p *claudeProvider) Profiles(cfg Config) []Provider {
+	var out []Provider
+	for _, pr := range cfg.Claude.Profiles {
+		if pr.Name == "" {
+			continue
+		}
+		out = append(out, &claudeProvider{profile: pr, tokenURL: cfg.Claude.TokenURL})
+	}
+	return out
+}
+
+// Login loads the OAuth token. It is safe to call more than once.
+func (p *claudeProvider) Login() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.creds.AccessToken != "" {
+		return nil
+	}
+	creds, _, err := p.loadToken()
+	if err != nil {
+		return err
+	}
+	p.creds = creds
+	return nil
+}
+
+func (p *claudeProvider) FetchLimits(ctx context.Context) (*RateLimits, error) {
+	if err := p.Login(); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	creds := p.creds
+	p.mu.Unlock()
+
+	refreshed := false
+	if creds.expiresSoon(time.Now()) {
+		// if this fails, the token is tried anyway and refreshed should
+		// the API reject it
+		if fresh, err := p.refresh(ctx, creds); err == nil {
+			creds, refreshed = fresh, true
+		}
+	}
+	usage, err := fetchUsage(ctx, creds.AccessToken)
+	if errors.Is(err, errTokenExpired) && !refreshed {
+		// revoked or expired early; one refresh, then give up
+		if creds, err = p.refresh(ctx, creds); err != nil {
+			return nil, err
+		}
+		usage, err = fetchUsage(ctx, creds.AccessToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+	limits := &RateLimits{Plan: creds.SubscriptionType, AsOf: time.Now()}
+	add := func(key, label, short string, length time.Duration, b *UsageBucket) {
+		if b == nil {
+			return
+		}
+		w := LimitWindow{Key: key, Label: label, Short: short, UsedPercent: b.Utilization, Length: length}
+		if b.ResetsAt != nil {
+			if t, err := time.Parse(time.RFC3339, *b.ResetsAt); err == nil {
+				w.ResetsAt = t
+			}
+		}
+		limits.Windows = append(limits.Windows, w)
+	}
+	add("5h", "Session (5h)", "5h", 5*time.Hour, usage.FiveHour)
+	add("7d", "Weekly (7d)", "7d", 7*24*time.Hour, usage.SevenDay)
+	add("opus", "Opus (7d)", "Opus", 7*24*time.Hour, usage.SevenDayOpus)
+	return limits, nil
+}
+
+func (p *claudeProvider) ScanUsage(ctx context.Context, since time.Time, emit func(UsageEvent)) error {
+	return scanClaudeUsage(ctx, p.Name(), claudeSessionDirs(p.profile.ConfigDir), since, emit)
+}
+
+func (p *claudeProvider) WatchDirs() []string {
+	return claudeSessionDirs(p.profile.ConfigDir)
+}
+
+func (p *claudeProvider) SessionFile(path string) (sessionFile, usageFunc, bool) {
+	if filepath.Ext(path) != ".jsonl" {
+		return sessionFile{}, nil, false
+	}
+	for _, root := range claudeSessionDirs(p.profile.ConfigDir) {
+		if withinDir(root, path) {
+			return claudeSessionFile(root, path), claudeUsage, true
+		}
+	}
+	return sessionFile{}, nil, false
+}
+
+// refresh replaces the expired credentials stale with fresh ones and
+// returns them. Claude Code refreshes the same token, and the server may
+// invalidate the old refresh token when either of them does, so the
+// credential source is re-read first and used if Claude Code has stored a
+// newer token. Otherwise the refresh token is only redeemed when the
+// credentials came from the credentials file, which the rotated tokens are
+// written back to; tokens from any other source wait for Claude Code.
+func (p *claudeProvider) refresh(ctx context.Context, stale OAuthEntry) (OAuthEntry, error) {
+	p.refreshMu.Lock()
+	defer p.refreshMu.Unlock()
+
+	// another fetch may have refreshed while we waited
+	p.mu.Lock()
+	current := p.creds
+	p.mu.Unlock()
+	if current.AccessToken != stale.AccessToken {
+		return current, nil
+	}
+
+	stored, src, err := p.loadToken()
+	switch {
+	case err != nil:
+		return OAuthEntry{}, err
+	case stored.AccessToken != stale.AccessToken && !stored.expiresSoon(time.Now()):
+		current = stored
+	case src.save != nil && stored.RefreshToken != "":
+		fresh, err := refreshOAuthToken(ctx, p.tokenURL, stored)
+		if err != nil {
+			return OAuthEntry{}, err
+		}
+		if err := src.save(fresh); err != nil {
+			// the old refresh token may be spent; keep the new one at least
+			p.mu.Lock()
+			p.creds = fresh
+			p.mu.Unlock()
+			return OAuthEntry{}, fmt.Errorf("failed to save refreshed token to %s: %w", src.name, err)
+		}
+		current = fresh
+	default:
+		return OAuthEntry{}, errTokenExpired
+	}
+
+	p.mu.Lock()
+	p.creds = current
+	p.mu.Unlock()
+	return current, nil
+}
+
+// claudeUsageURL is the OAuth usage API endpoint.
+var claudeUsageURL = "https://api.anthropic.com/api/oauth/usage"
+
+func fetchUsage(ctx context.Context, token string) (*UsageResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", claudeUsageURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -38,7 +223,7 @@ func fetchUsage(token string) (*UsageResponse, error) {
 	}
 
 	if resp.StatusCode == 401 {
-		return nil, fmt.Errorf("token expired — re-login to Claude Code")
+		return nil, errTokenExpired
 	}
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("API error (HTTP %d): %s", resp.StatusCode, string(body))
@@ -2,11 +2,13 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 )
 
@@ -14,6 +16,7 @@ import (
 type CodexUsage struct {
 	Primary   *CodexBucket
 	Secondary *CodexBucket
+	AsOf      time.Time // when Codex recorded these limits; zero if unknown
 }
 
 // CodexBucket represents one rate-limit window (primary=5h, secondary=weekly).
@@ -53,6 +56,83 @@ type codexBucketJSON struct {
 	ResetsAt      int64   `json:"resets_at"`
 }
 
+func init() {
+	registerProvider(codexProvider{})
+}
+
+// codexProvider reports Codex rate limits and token counts, both parsed
+// from the Codex CLI's local session files.
+type codexProvider struct{}
+
+func (codexProvider) Name() string  { return "codex" }
+func (codexProvider) Title() string { return "Codex" }
+
+func (codexProvider) FetchLimits(ctx context.Context) (*RateLimits, error) {
+	usage, err := fetchCodexUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	limits := &RateLimits{AsOf: usage.AsOf}
+	add := func(key, label, short string, b *CodexBucket) {
+		if b == nil {
+			return
+		}
+		w := LimitWindow{
+			Key:         key,
+			Label:       label,
+			Short:       short,
+			UsedPercent: b.UsedPercent,
+			Length:      time.Duration(b.WindowMinutes) * time.Minute,
+		}
+		if b.ResetsAt > 0 {
+			w.ResetsAt = b.ResetsAtTime()
+		}
+		limits.Windows = append(limits.Windows, w)
+	}
+	add("5h", "Session (5h)", "5h", usage.Primary)
+	add("7d", "Weekly (7d)", "7d", usage.Secondary)
+	return limits, nil
+}
+
+func (codexProvider) ScanUsage(ctx context.Context, since time.Time, emit func(UsageEvent)) error {
+	return scanCodexUsage(ctx, since, emit)
+}
+
+func (codexProvider) WatchDirs() []string {
+	return existingDirs(codexSessionDir())
+}
+
+func (codexProvider) SessionFile(path string) (sessionFile, usageFunc, bool) {
+	if filepath.Ext(path) != ".jsonl" || !withinDir(codexSessionDir(), path) {
+		return sessionFile{}, nil, false
+	}
+	return codexSessionFile(path), codexUsage, true
+}
+
+// LimitHistory returns the rate limits recorded in every Codex session
+// file, one sample per window per token_count event.
+func (codexProvider) LimitHistory(ctx context.Context) ([]historySample, error) {
+	dir := codexSessionDir()
+	if _, err := os.Stat(dir); err != nil {
+		return nil, nil // not installed
+	}
+	var samples []historySample
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".jsonl" {
+			return nil
+		}
+		samples = append(samples, parseCodexLimitHistory(path)...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan codex sessions: %w", err)
+	}
+	return samples, nil
+}
+
 func codexSessionDir() string {
 	if home := os.Getenv("CODEX_HOME"); home != "" {
 		return filepath.Join(home, "sessions")
@@ -65,7 +145,7 @@ func codexSessionDir() string {
 }
 
 // fetchCodexUsage scans the most recent Codex session files for rate_limits.
-func fetchCodexUsage() (*CodexUsage, error) {
+func fetchCodexUsage(ctx context.Context) (*CodexUsage, error) {
 	dir := codexSessionDir()
 	if dir == "" {
 		return nil, fmt.Errorf("codex sessions directory not found")
@@ -77,6 +157,9 @@ func fetchCodexUsage() (*CodexUsage, error) {
 	// Find all jsonl files and sort by modification time (newest first)
 	var files []string
 	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		if err != nil {
 			return nil
 		}
@@ -108,6 +191,9 @@ func fetchCodexUsage() (*CodexUsage, error) {
 	}
 
 	for _, f := range files[:limit] {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		usage, err := parseCodexFile(f)
 		if err == nil && usage != nil {
 			return usage, nil
@@ -117,18 +203,31 @@ func fetchCodexUsage() (*CodexUsage, error) {
 	return nil, fmt.Errorf("no rate limit data found in recent codex sessions")
 }
 
-// scanCodexTokens scans Codex session files for token usage since the given time.
-func scanCodexTokens(since time.Time) (TokenStats, error) {
-	var stats TokenStats
+// scanCodexUsage emits the usage of every Codex session since the given time.
+func scanCodexUsage(ctx context.Context, since time.Time, emit func(UsageEvent)) error {
+	files, err := codexFiles(ctx, since)
+	if err != nil {
+		return err
+	}
+	return scanFiles(ctx, "codex", files, codexUsage, since, emit)
+}
+
+// codexFiles lists the Codex session files modified since the given time.
+// A missing sessions directory means Codex isn't installed: no files.
+func codexFiles(ctx context.Context, since time.Time) ([]sessionFile, error) {
 	dir := codexSessionDir()
 	if dir == "" {
-		return stats, fmt.Errorf("codex sessions directory not found")
+		return nil, fmt.Errorf("codex sessions directory not found")
 	}
 	if _, err := os.Stat(dir); err != nil {
-		return stats, nil // not installed, return zeros
+		return nil, nil // not installed
 	}
 
+	var files []sessionFile
 	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		if err != nil {
 			return nil
 		}
@@ -138,175 +237,179 @@ func scanCodexTokens(since time.Time) (TokenStats, error) {
 		if info, err := d.Info(); err == nil && info.ModTime().Before(since) {
 			return nil
 		}
-		scanCodexFileTokens(path, since, &stats)
+		files = append(files, codexSessionFile(path))
 		return nil
 	})
-	if err != nil {
-		return stats, err
+	return files, err
+}
+
+// codexSessionFile describes the session file at path.
+func codexSessionFile(path string) sessionFile {
+	return sessionFile{
+		path:  path,
+		id:    strings.TrimSuffix(filepath.Base(path), ".jsonl"),
+		parse: parseCodexLine,
 	}
-	return stats, nil
 }
 
-// scanCodexFileTokens reads a single Codex session file and adds its token usage.
-// Uses the last total_token_usage entry as the session total.
-func scanCodexFileTokens(path string, since time.Time, stats *TokenStats) {
-	f, err := os.Open(path)
-	if err != nil {
+// parseCodexLine records every token_count snapshot, tagged with the model
+// and working directory most recently announced by the session.
+func parseCodexLine(st *fileState, line []byte) {
+	var entry codexJSONLEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
 		return
 	}
-	defer f.Close()
-
-	var lastInfo *codexTokenInfo
-	var lastTimestamp string
-
-	scanner := bufio.NewScanner(f)
-	scanner.Buffer(make([]byte, 512*1024), 512*1024)
-	for scanner.Scan() {
-		var entry codexJSONLEntry
-		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
-			continue
-		}
-		if entry.Type != "event_msg" || entry.Payload == nil {
-			continue
-		}
-
-		var payload codexTokenPayload
-		if err := json.Unmarshal(entry.Payload, &payload); err != nil {
-			continue
-		}
-		if payload.Type != "token_count" || payload.Info == nil {
-			continue
-		}
-		if payload.Info.TotalTokenUsage == nil {
-			continue
+	ts, tsErr := time.Parse(time.RFC3339Nano, entry.Timestamp)
+	if st.First == 0 && tsErr == nil {
+		st.First = ts.UnixNano()
+	}
+	if entry.Payload == nil {
+		return
+	}
+	if entry.Type == "turn_context" || entry.Type == "session_meta" {
+		var tc codexTurnContext
+		if err := json.Unmarshal(entry.Payload, &tc); err == nil {
+			if tc.Model != "" {
+				st.Model = tc.Model
+			}
+			if tc.Cwd != "" {
+				st.Cwd = tc.Cwd
+			}
 		}
-
-		lastInfo = payload.Info
-		lastTimestamp = entry.Timestamp
+		return
 	}
-
-	if lastInfo == nil || lastInfo.TotalTokenUsage == nil {
+	if entry.Type != "event_msg" {
 		return
 	}
 
-	// Check timestamp is within our window
-	if lastTimestamp != "" {
-		ts, err := time.Parse(time.RFC3339Nano, lastTimestamp)
-		if err == nil && ts.Before(since) {
-			return
-		}
+	var payload codexTokenPayload
+	if err := json.Unmarshal(entry.Payload, &payload); err != nil {
+		return
+	}
+	if payload.Type != "token_count" || payload.Info == nil || payload.Info.TotalTokenUsage == nil {
+		return
 	}
 
-	tu := lastInfo.TotalTokenUsage
+	tu := payload.Info.TotalTokenUsage
 	// input_tokens includes cached, so non-cached = input - cached
 	nonCached := tu.InputTokens - tu.CachedInputTokens
 	if nonCached < 0 {
 		nonCached = 0
 	}
-	stats.InputTokens += nonCached
-	stats.CacheRead += tu.CachedInputTokens
-	stats.OutputTokens += tu.OutputTokens
-	// CacheCreation stays 0 for Codex (no equivalent field)
-}
-
-// scanCodexTokensByDay scans Codex session files and buckets token usage by day of month.
-func scanCodexTokensByDay(year int, month time.Month) (DailyTokenStats, error) {
-	daily := make(DailyTokenStats)
-	dir := codexSessionDir()
-	if dir == "" {
-		return daily, nil
+	// likewise output_tokens includes reasoning_output_tokens
+	visible := tu.OutputTokens - tu.ReasoningOutputTokens
+	if visible < 0 {
+		visible = 0
 	}
-	if _, err := os.Stat(dir); err != nil {
-		return daily, nil
+	project := ""
+	if st.Cwd != "" {
+		project = projectRoot(st.Cwd)
+	}
+	if tsErr != nil {
+		ts = time.Time{}
 	}
+	st.add(ts, "", UsageKey{Model: st.Model, Project: project}, TokenStats{
+		InputTokens:  nonCached,
+		CacheRead:    tu.CachedInputTokens,
+		OutputTokens: visible,
+		Reasoning:    tu.ReasoningOutputTokens,
+		// CacheCreation stays 0 for Codex (no equivalent field)
+	})
+}
 
-	loc := time.Now().Location()
-	since := time.Date(year, month, 1, 0, 0, 0, 0, loc)
-	until := since.AddDate(0, 1, 0)
+// codexUsage reports the growth of total_token_usage between successive
+// snapshots, each at the time of its own snapshot, so a session spanning
+// several days is spread over them.
+func codexUsage(st *fileState, since time.Time, emit func(time.Time, string, UsageKey, TokenStats)) {
+	cumulativeUsage(st, since, emit)
+}
 
-	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
-		if err != nil || d.IsDir() || filepath.Ext(path) != ".jsonl" {
-			return nil
-		}
-		if info, err := d.Info(); err == nil && info.ModTime().Before(since) {
-			return nil
-		}
-		scanCodexFileTokensByDay(path, since, until, daily)
-		return nil
-	})
-	return daily, nil
+// codexTurnContext is the payload of a turn_context entry, written at the
+// start of every turn with the model and settings in effect. session_meta
+// entries share the cwd field.
+type codexTurnContext struct {
+	Model string `json:"model"`
+	Cwd   string `json:"cwd"`
 }
 
-func scanCodexFileTokensByDay(path string, since, until time.Time, daily DailyTokenStats) {
+type codexTokenPayload struct {
+	Type string          `json:"type"`
+	Info *codexTokenInfo `json:"info"`
+}
+
+type codexTokenInfo struct {
+	TotalTokenUsage *codexTokenUsage `json:"total_token_usage"`
+}
+
+type codexTokenUsage struct {
+	InputTokens           int `json:"input_tokens"`
+	CachedInputTokens     int `json:"cached_input_tokens"`
+	OutputTokens          int `json:"output_tokens"`
+	ReasoningOutputTokens int `json:"reasoning_output_tokens"`
+	TotalTokens           int `json:"total_tokens"`
+}
+
+// parseCodexLimitHistory returns a sample per window of every rate_limits
+// entry in a session file. Like parseCodexFile, it keeps to the first
+// limit_id with non-zero usage.
+func parseCodexLimitHistory(path string) []historySample {
 	f, err := os.Open(path)
 	if err != nil {
-		return
+		return nil
 	}
 	defer f.Close()
 
-	var lastInfo *codexTokenInfo
-	var lastTS time.Time
-
+	type entry struct {
+		t  time.Time
+		rl *codexRateLimit
+	}
+	var entries []entry
+	preferred, found := "", false
 	scanner := bufio.NewScanner(f)
 	scanner.Buffer(make([]byte, 512*1024), 512*1024)
 	for scanner.Scan() {
-		var entry codexJSONLEntry
-		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
-			continue
-		}
-		if entry.Type != "event_msg" || entry.Payload == nil {
+		var e codexJSONLEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || e.Type != "event_msg" || e.Payload == nil {
 			continue
 		}
-		var payload codexTokenPayload
-		if err := json.Unmarshal(entry.Payload, &payload); err != nil {
+		var payload codexPayload
+		if err := json.Unmarshal(e.Payload, &payload); err != nil {
 			continue
 		}
-		if payload.Type != "token_count" || payload.Info == nil || payload.Info.TotalTokenUsage == nil {
+		if payload.Type != "token_count" || payload.RateLimits == nil {
 			continue
 		}
-		ts, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
+		t, err := time.Parse(time.RFC3339Nano, e.Timestamp)
 		if err != nil {
 			continue
 		}
-		lastInfo = payload.Info
-		lastTS = ts
+		rl := payload.RateLimits
+		if !found && (rl.Primary != nil && rl.Primary.UsedPercent > 0 || rl.Secondary != nil && rl.Secondary.UsedPercent > 0) {
+			preferred, found = rl.LimitID, true
+		}
+		entries = append(entries, entry{t, rl})
 	}
 
-	if lastInfo == nil || lastInfo.TotalTokenUsage == nil {
-		return
-	}
-	if lastTS.Before(since) || !lastTS.Before(until) {
-		return
+	var samples []historySample
+	add := func(t time.Time, window string, b *codexBucketJSON) {
+		if b == nil {
+			return
+		}
+		s := historySample{Time: t, Provider: "codex", Window: window, UsedPercent: b.UsedPercent}
+		if b.ResetsAt > 0 {
+			resetsAt := time.Unix(b.ResetsAt, 0)
+			s.ResetsAt = &resetsAt
+		}
+		samples = append(samples, s)
 	}
-
-	tu := lastInfo.TotalTokenUsage
-	nonCached := tu.InputTokens - tu.CachedInputTokens
-	if nonCached < 0 {
-		nonCached = 0
+	for _, e := range entries {
+		if found && e.rl.LimitID != preferred {
+			continue
+		}
+		add(e.t, "5h", e.rl.Primary)
+		add(e.t, "7d", e.rl.Secondary)
 	}
-	day := lastTS.Day()
-	s := daily[day]
-	s.InputTokens += nonCached
-	s.CacheRead += tu.CachedInputTokens
-	s.OutputTokens += tu.OutputTokens
-	daily[day] = s
-}
-
-type codexTokenPayload struct {
-	Type string         `json:"type"`
-	Info *codexTokenInfo `json:"info"`
-}
-
-type codexTokenInfo struct {
-	TotalTokenUsage *codexTokenUsage `json:"total_token_usage"`
-}
-
-type codexTokenUsage struct {
-	InputTokens           int `json:"input_tokens"`
-	CachedInputTokens     int `json:"cached_input_tokens"`
-	OutputTokens          int `json:"output_tokens"`
-	ReasoningOutputTokens int `json:"reasoning_output_tokens"`
-	TotalTokens           int `json:"total_tokens"`
+	return samples
 }
 
 // parseCodexFile reads a single session file and returns the last rate_limits entry.
@@ -321,6 +424,7 @@ func parseCodexFile(path string) (*CodexUsage, error) {
 
 	// Track last rate_limits per limit_id
 	lastByID := make(map[string]*codexRateLimit)
+	var asOf time.Time
 
 	scanner := bufio.NewScanner(f)
 	scanner.Buffer(make([]byte, 512*1024), 512*1024)
@@ -348,6 +452,9 @@ func parseCodexFile(path string) (*CodexUsage, error) {
 			id = "_default"
 		}
 		lastByID[id] = payload.RateLimits
+		if ts, err := time.Parse(time.RFC3339Nano, entry.Timestamp); err == nil && ts.After(asOf) {
+			asOf = ts
+		}
 	}
 
 	if len(lastByID) == 0 {
@@ -373,7 +480,7 @@ func parseCodexFile(path string) (*CodexUsage, error) {
 	}
 
 	now := time.Now()
-	usage := &CodexUsage{}
+	usage := &CodexUsage{AsOf: asOf}
 	if lastRL.Primary != nil {
 		b := &CodexBucket{
 			UsedPercent:   lastRL.Primary.UsedPercent,
@@ -5,29 +5,59 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 // Config holds user preferences for which providers to display.
 type Config struct {
 	Providers ProviderConfig `json:"providers"`
+	// Pricing overrides or extends the built-in per-model prices.
+	Pricing Pricing `json:"pricing,omitempty"`
+	// Formats holds named --format templates. The one named "default" is
+	// used by --compact when no --format is given.
+	Formats map[string]string `json:"formats,omitempty"`
+	// CacheTTL is how long compact and JSON output reuse a cached result,
+	// e.g. "30s" or "2m"; "0" disables the cache. Defaults to a minute.
+	CacheTTL string `json:"cache_ttl,omitempty"`
+	// Claude holds settings for the Claude provider.
+	Claude ClaudeConfig `json:"claude,omitzero"`
 }
 
-// ProviderConfig holds visibility settings for each provider.
-type ProviderConfig struct {
-	Claude bool `json:"claude"`
-	Codex  bool `json:"codex"`
-	Kimi   bool `json:"kimi"`
+// ClaudeConfig holds settings for the Claude provider.
+type ClaudeConfig struct {
+	// TokenURL is the OAuth endpoint expired access tokens are refreshed
+	// at. Defaults to Anthropic's.
+	TokenURL string `json:"token_url,omitempty"`
+	// CredentialsCommand is run, as program and arguments, when no other
+	// source has credentials. It prints Claude Code's credentials JSON or
+	// a bare access token.
+	CredentialsCommand []string `json:"credentials_command,omitempty"`
+	// Profiles are further Claude accounts, each tracked as its own
+	// provider named "claude-<name>".
+	Profiles []ClaudeProfile `json:"profiles,omitempty"`
 }
 
+// ClaudeProfile is a Claude account with its own Claude Code config dir,
+// as selected by CLAUDE_CONFIG_DIR.
+type ClaudeProfile struct {
+	Name string `json:"name"`
+	// ConfigDir holds the profile's credentials file and session files.
+	ConfigDir string `json:"config_dir"`
+	// CredentialsCommand is tried when ConfigDir has no credentials file.
+	CredentialsCommand []string `json:"credentials_command,omitempty"`
+}
+
+// ProviderConfig maps provider names to their visibility. Providers
+// missing from the map are enabled.
+type ProviderConfig map[string]bool
+
 // DefaultConfig returns the default configuration (all providers enabled).
 func DefaultConfig() Config {
-	return Config{
-		Providers: ProviderConfig{
-			Claude: true,
-			Codex:  true,
-			Kimi:   true,
-		},
+	cfg := Config{Providers: make(ProviderConfig)}
+	for _, p := range providers {
+		cfg.Providers[p.Name()] = true
 	}
+	return cfg
 }
 
 // configDir returns the configuration directory.
@@ -88,23 +118,50 @@ func (c Config) Save() error {
 	return nil
 }
 
+// PricingTable returns the built-in prices with the user's overrides applied.
+func (c Config) PricingTable() Pricing {
+	table := make(Pricing, len(defaultPricing)+len(c.Pricing))
+	for prefix, p := range defaultPricing {
+		table[prefix] = p
+	}
+	for prefix, p := range c.Pricing {
+		table[prefix] = p
+	}
+	return table
+}
+
+// SnapshotTTL returns the parsed CacheTTL, or the default if it is unset
+// or invalid.
+func (c Config) SnapshotTTL() time.Duration {
+	if c.CacheTTL == "" {
+		return defaultCacheTTL
+	}
+	ttl, err := time.ParseDuration(c.CacheTTL)
+	if err != nil {
+		return defaultCacheTTL
+	}
+	return ttl
+}
+
+// Enabled returns true if the named provider should be displayed.
+func (c Config) Enabled(name string) bool {
+	on, ok := c.Providers[name]
+	return !ok || on
+}
+
 // AnyEnabled returns true if at least one provider is enabled.
 func (c Config) AnyEnabled() bool {
-	return c.Providers.Claude || c.Providers.Codex || c.Providers.Kimi
+	return len(enabledProviders(c)) > 0
 }
 
 // ToggleProvider toggles the visibility of a provider.
 func (c *Config) ToggleProvider(name string) bool {
-	switch name {
-	case "claude":
-		c.Providers.Claude = !c.Providers.Claude
-		return c.Providers.Claude
-	case "codex":
-		c.Providers.Codex = !c.Providers.Codex
-		return c.Providers.Codex
-	case "kimi":
-		c.Providers.Kimi = !c.Providers.Kimi
-		return c.Providers.Kimi
+	if lookupProvider(name) == nil {
+		return false
+	}
+	if c.Providers == nil {
+		c.Providers = make(ProviderConfig)
 	}
-	return false
+	c.Providers[name] = !c.Enabled(name)
+	return c.Providers[name]
 }
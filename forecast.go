@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// minForecastSpan is the shortest stretch of samples a burn rate is
+// computed from; over less, a single message skews it too much.
+const minForecastSpan = 10 * time.Minute
+
+// forecast is where a rate-limit window is heading at its current burn
+// rate.
+type forecast struct {
+	PercentPerHour float64
+	// ExhaustsAt is when the window reaches 100% at that rate; zero if it
+	// resets first.
+	ExhaustsAt time.Time
+}
+
+// OnTrack reports whether the window resets before it runs out.
+func (f forecast) OnTrack() bool {
+	return f.ExhaustsAt.IsZero()
+}
+
+// forecastWindow projects the window w of provider, reported at asOf, from
+// its recorded samples: the burn rate is the rise in percent used over the
+// last fifth of the window length, counting only samples since the last
+// reset. It returns false when there are too few samples to tell.
+func forecastWindow(history []historySample, provider string, w LimitWindow, asOf time.Time) (forecast, bool) {
+	if !w.ResetsAt.IsZero() && !w.ResetsAt.After(asOf) {
+		return forecast{}, false // already reset; the reported usage is over
+	}
+	lookback := max(w.Length/5, time.Hour)
+	from := asOf.Add(-lookback)
+	if !w.ResetsAt.IsZero() && w.Length > 0 {
+		from = maxTime(from, w.ResetsAt.Add(-w.Length))
+	}
+
+	var first *historySample
+	for i := range history {
+		s := &history[i]
+		if s.Provider != provider || s.Window != w.Key || s.Time.Before(from) || s.Time.After(asOf) {
+			continue
+		}
+		if !w.ResetsAt.IsZero() && s.ResetsAt != nil && !sameReset(*s.ResetsAt, w.ResetsAt) {
+			continue // an earlier period of the window
+		}
+		if first == nil || s.Time.Before(first.Time) {
+			first = s
+		}
+	}
+	if first == nil || asOf.Sub(first.Time) < minForecastSpan {
+		return forecast{}, false
+	}
+
+	f := forecast{PercentPerHour: (w.UsedPercent - first.UsedPercent) / asOf.Sub(first.Time).Hours()}
+	if f.PercentPerHour <= 0 {
+		return f, true
+	}
+	left := max(0, 100-w.UsedPercent)
+	out := asOf.Add(time.Duration(left / f.PercentPerHour * float64(time.Hour)))
+	if w.ResetsAt.IsZero() || out.Before(w.ResetsAt) {
+		f.ExhaustsAt = out
+	}
+	return f, true
+}
+
+// sameReset reports whether two reported reset times are the same reset.
+// Providers round them differently from one report to the next.
+func sameReset(a, b time.Time) bool {
+	d := a.Sub(b)
+	return d > -10*time.Minute && d < 10*time.Minute
+}
+
+// formatExhaustion renders when a window runs out: "≈ out at 16:40", or
+// with the day if that isn't today.
+func formatExhaustion(t, now time.Time) string {
+	t = t.Local()
+	if y, m, d := t.Date(); y == now.Year() && m == now.Month() && d == now.Day() {
+		return "≈ out at " + t.Format("15:04")
+	}
+	return "≈ out " + t.Format("Mon 15:04")
+}
+
+// windowElapsed returns how far into its current period the window is at
+// now, in percent of its length. It returns false if the window's length
+// or reset time is unknown, or the period is over.
+func windowElapsed(w LimitWindow, now time.Time) (float64, bool) {
+	if w.Length <= 0 || w.ResetsAt.IsZero() || !now.Before(w.ResetsAt) {
+		return 0, false
+	}
+	start := w.ResetsAt.Add(-w.Length)
+	if now.Before(start) {
+		return 0, false
+	}
+	return 100 * float64(now.Sub(start)) / float64(w.Length), true
+}
+
+// paceTolerance is how many points off an even pace still count as on
+// pace.
+const paceTolerance = 2
+
+// formatPace compares the percent used to the percent of the window
+// elapsed: "12% ahead" is 12 points more used than an even pace would
+// have by now.
+func formatPace(used, elapsed float64) string {
+	switch d := used - elapsed; {
+	case d > paceTolerance:
+		return fmt.Sprintf("%.0f%% ahead", d)
+	case d < -paceTolerance:
+		return fmt.Sprintf("%.0f%% behind", -d)
+	default:
+		return "on pace"
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateData is what a --format template is executed against. Claude,
+// Codex and Kimi and their 5h and 7d windows are always set, zero when
+// the provider is disabled or its limits couldn't be fetched, so a
+// template never fails on a network error; check .OK to tell. The Opus
+// window is nil unless reported, so guard it with
+// {{with .Claude.Opus}}...{{end}}.
+type templateData struct {
+	Claude *templateProvider
+	Codex  *templateProvider
+	Kimi   *templateProvider
+	// Providers holds every enabled provider by name.
+	Providers map[string]*templateProvider
+	Tokens    TokenWindows // all providers combined
+	Now       time.Time
+}
+
+// templateProvider is one provider in a template.
+type templateProvider struct {
+	Name     string
+	Plan     string
+	OK       bool   // the windows hold fetched limits, fresh or stale
+	Error    string // fetching the limits failed
+	Stale    bool   // the windows are the last good values, not fresh ones
+	FiveHour *templateWindow
+	SevenDay *templateWindow
+	Opus     *templateWindow
+	Windows  []*templateWindow // the windows reported, in order
+	Tokens   TokenWindows
+}
+
+// templateWindow is one rate-limit window in a template.
+type templateWindow struct {
+	Key       string
+	Label     string
+	Used      float64   // percent
+	Remaining float64   // percent
+	ResetsAt  time.Time // zero if unknown
+	// ExhaustsAt is when the window runs out at its current burn rate;
+	// zero if it resets first or there isn't enough history to tell.
+	ExhaustsAt time.Time
+	OnTrack    bool // enough history, and the window resets first
+}
+
+// newTemplateData builds the template model from a snapshot.
+func newTemplateData(snap Snapshot) templateData {
+	d := templateData{
+		Providers: make(map[string]*templateProvider),
+		Tokens:    snap.Tokens,
+		Now:       snap.GeneratedAt,
+	}
+	for _, ps := range snap.Providers {
+		p := &templateProvider{
+			Name:   ps.Name,
+			Plan:   ps.Plan,
+			OK:     ps.Error == "" || ps.Stale,
+			Error:  ps.Error,
+			Stale:  ps.Stale,
+			Tokens: ps.Tokens,
+		}
+		for _, l := range ps.Limits {
+			w := &templateWindow{Key: l.Key, Label: l.Label, Used: l.UsedPercent, Remaining: l.RemainingPercent}
+			if l.ResetsAt != nil {
+				w.ResetsAt = *l.ResetsAt
+			}
+			if f := l.Forecast; f != nil {
+				w.OnTrack = f.OnTrack
+				if f.ExhaustsAt != nil {
+					w.ExhaustsAt = *f.ExhaustsAt
+				}
+			}
+			switch l.Key {
+			case "5h":
+				p.FiveHour = w
+			case "7d":
+				p.SevenDay = w
+			case "opus":
+				p.Opus = w
+			}
+			p.Windows = append(p.Windows, w)
+		}
+		if p.FiveHour == nil {
+			p.FiveHour = &templateWindow{Key: "5h"}
+		}
+		if p.SevenDay == nil {
+			p.SevenDay = &templateWindow{Key: "7d"}
+		}
+		d.Providers[ps.Name] = p
+	}
+	provider := func(name string) *templateProvider {
+		if p, ok := d.Providers[name]; ok {
+			return p
+		}
+		return &templateProvider{Name: name, FiveHour: &templateWindow{Key: "5h"}, SevenDay: &templateWindow{Key: "7d"}}
+	}
+	d.Claude, d.Codex, d.Kimi = provider("claude"), provider("codex"), provider("kimi")
+	return d
+}
+
+// templateFuncs are the helpers available to --format templates.
+var templateFuncs = template.FuncMap{
+	// pct renders a percentage: 45.2 → "45%".
+	"pct": func(p float64) string {
+		return fmt.Sprintf("%.0f%%", p)
+	},
+	// tokens renders a token count: 1234567 → "1.2M".
+	"tokens": formatTokenCount,
+	// usd renders a cost; an unknown cost renders as "-".
+	"usd": func(usd *float64) string {
+		if usd == nil {
+			return "-"
+		}
+		return formatCost(*usd)
+	},
+	// until renders the time left until t: "2h 47m", "3d 4h"; empty if t
+	// is unknown.
+	"until": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		d := time.Until(t)
+		switch {
+		case d <= 0:
+			return "0m"
+		case d < time.Hour:
+			return fmt.Sprintf("%dm", int(math.Ceil(d.Minutes())))
+		case d < 24*time.Hour:
+			return formatDuration(d)
+		default:
+			return fmt.Sprintf("%dd %dh", int(d.Hours())/24, int(d.Hours())%24)
+		}
+	},
+	// bar renders a percentage as a 10-cell bar: 40 → "▰▰▰▰▱▱▱▱▱▱".
+	"bar": func(p float64) string {
+		return textBar(p, 10)
+	},
+}
+
+// textBar renders a percentage as a bar of width cells.
+func textBar(p float64, width int) string {
+	filled := int(math.Round(max(0, min(p, 100)) / 100 * float64(width)))
+	return strings.Repeat("▰", filled) + strings.Repeat("▱", width-filled)
+}
+
+// resolveFormat returns the template named name in cfg, or name itself if
+// no saved format has that name.
+func resolveFormat(cfg Config, name string) string {
+	if tpl, ok := cfg.Formats[name]; ok {
+		return tpl
+	}
+	return name
+}
+
+// parseFormat parses a --format template.
+func parseFormat(text string) (*template.Template, error) {
+	return template.New("format").Funcs(templateFuncs).Parse(text)
+}
+
+// writeTemplate renders the snapshot through tpl, ending with a newline.
+func writeTemplate(w io.Writer, tpl *template.Template, snap Snapshot) error {
+	var b strings.Builder
+	if err := tpl.Execute(&b, newTemplateData(snap)); err != nil {
+		return err
+	}
+	out := strings.TrimSuffix(b.String(), "\n")
+	_, err := fmt.Fprintln(w, out)
+	return err
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// historySample is one recorded utilization of a rate-limit window.
+type historySample struct {
+	Time        time.Time  `json:"time"` // when the provider reported it
+	Provider    string     `json:"provider"`
+	Window      string     `json:"window"` // LimitWindow.Key
+	UsedPercent float64    `json:"used_percent"`
+	ResetsAt    *time.Time `json:"resets_at,omitempty"`
+}
+
+// historyKey identifies a sample: the same window reported at the same
+// time is the same sample.
+type historyKey struct {
+	provider, window string
+	time             int64
+}
+
+func (s historySample) key() historyKey {
+	return historyKey{s.Provider, s.Window, s.Time.UnixNano()}
+}
+
+// historyProvider is implemented by providers that kept their past rate
+// limits locally, so the history can be backfilled from before llm-usage
+// started recording.
+type historyProvider interface {
+	LimitHistory(ctx context.Context) ([]historySample, error)
+}
+
+// dataDir returns the directory for data worth keeping, unlike the cache.
+func dataDir() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "llm-usage")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".local", "share", "llm-usage")
+}
+
+// historyDir returns the directory of the history files: one per month,
+// named YYYY-MM.jsonl after the UTC month its samples were reported in,
+// with one JSON sample per line, appended to and never rewritten.
+// Partitioning keeps a read of the last few days from touching older
+// months.
+func historyDir() string {
+	return filepath.Join(dataDir(), "history")
+}
+
+// historyPath returns the history file holding samples reported at t.
+func historyPath(t time.Time) string {
+	return filepath.Join(historyDir(), t.UTC().Format("2006-01")+".jsonl")
+}
+
+// processStart is when this process started. Limits reported since can't
+// have been recorded by another process.
+var processStart = time.Now()
+
+// lastRecorded remembers, per provider, when the limits recorded last were
+// reported. Codex reports the same limits until its next session, and
+// those are recorded once.
+var lastRecorded struct {
+	sync.Mutex
+	asOf map[string]time.Time
+}
+
+// newHistorySamples returns a sample for each window in limits.
+func newHistorySamples(provider string, limits *RateLimits) []historySample {
+	t := limits.AsOf
+	if t.IsZero() {
+		t = time.Now()
+	}
+	var out []historySample
+	for _, w := range limits.Windows {
+		s := historySample{Time: t, Provider: provider, Window: w.Key, UsedPercent: w.UsedPercent}
+		if !w.ResetsAt.IsZero() {
+			resetsAt := w.ResetsAt
+			s.ResetsAt = &resetsAt
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// recordLimits appends the provider's limits to the history, unless they
+// are the ones recorded last. Limits reported before this process started,
+// as Codex's are, are looked up in the history first.
+func recordLimits(provider string, limits *RateLimits) error {
+	lastRecorded.Lock()
+	if lastRecorded.asOf == nil {
+		lastRecorded.asOf = make(map[string]time.Time)
+	}
+	last, ok := lastRecorded.asOf[provider]
+	lastRecorded.asOf[provider] = limits.AsOf
+	if !limits.AsOf.IsZero() && limits.AsOf.Equal(last) {
+		lastRecorded.Unlock()
+		return nil
+	}
+	if !ok && !limits.AsOf.IsZero() && limits.AsOf.Before(processStart) && isRecorded(provider, limits.AsOf) {
+		lastRecorded.Unlock()
+		return nil
+	}
+	lastRecorded.Unlock()
+	return appendHistory(newHistorySamples(provider, limits))
+}
+
+// isRecorded reports whether the history holds limits of provider
+// reported at asOf. The file of that month is read backwards from its end,
+// a chunk at a time, since repeated limits were usually recorded recently;
+// lines are matched on their leading time and provider, without decoding.
+func isRecorded(provider string, asOf time.Time) bool {
+	f, err := os.Open(historyPath(asOf))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	// the start of the line json.Marshal writes for such a sample
+	t, _ := json.Marshal(asOf)
+	name, _ := json.Marshal(provider)
+	prefix := append(append(append([]byte(`{"time":`), t...), `,"provider":`...), name...)
+
+	const chunkSize = 64 << 10
+	var partial []byte // start of the first line of the chunk after
+	for end := info.Size(); end > 0; {
+		start := max(0, end-chunkSize)
+		buf := make([]byte, end-start, end-start+int64(len(partial)))
+		if _, err := f.ReadAt(buf, start); err != nil {
+			return false
+		}
+		lines := bytes.Split(append(buf, partial...), []byte("\n"))
+		partial = nil
+		if start > 0 {
+			partial, lines = lines[0], lines[1:]
+		}
+		for _, line := range lines {
+			if bytes.HasPrefix(line, prefix) {
+				return true
+			}
+		}
+		end = start
+	}
+	return false
+}
+
+// appendHistory appends samples to the history files of their months. A
+// file is locked while writing, since the TUI, status bars and the record
+// command may all record at once.
+func appendHistory(samples []historySample) error {
+	data := make(map[string][]byte)
+	var paths []string
+	for _, s := range samples {
+		line, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+		path := historyPath(s.Time)
+		if _, ok := data[path]; !ok {
+			paths = append(paths, path)
+		}
+		data[path] = append(append(data[path], line...), '\n')
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(historyDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	for _, path := range paths {
+		if err := appendHistoryFile(path, data[path]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendHistoryFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+	if err := lockFile(f); err != nil {
+		return fmt.Errorf("failed to lock history: %w", err)
+	}
+	defer unlockFile(f)
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// readHistory returns the samples recorded since the given time, oldest
+// first, reading only the files of the months since then. Samples recorded
+// twice, by concurrent recorders or a repeated backfill, are returned
+// once; unreadable lines are skipped.
+func readHistory(since time.Time) ([]historySample, error) {
+	entries, err := os.ReadDir(historyDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	first := filepath.Base(historyPath(since))
+
+	seen := make(map[historyKey]bool)
+	var out []historySample
+	for _, e := range entries {
+		// YYYY-MM.jsonl sorts by month, and ReadDir sorts by name
+		if name := e.Name(); len(name) != len("2006-01.jsonl") || filepath.Ext(name) != ".jsonl" || name < first {
+			continue
+		}
+		if err := readHistoryFile(filepath.Join(historyDir(), e.Name()), since, seen, &out); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	return out, nil
+}
+
+// readHistoryFile appends the samples in path since the given time to out,
+// skipping those in seen. Lines start with the sample's time, so older
+// ones are skipped without decoding them.
+func readHistoryFile(path string, since time.Time, seen map[historyKey]bool, out *[]historySample) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+	defer f.Close()
+
+	prefix := []byte(`{"time":"`)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if rest, ok := bytes.CutPrefix(line, prefix); ok {
+			if i := bytes.IndexByte(rest, '"'); i > 0 {
+				if t, err := time.Parse(time.RFC3339Nano, string(rest[:i])); err == nil && t.Before(since) {
+					continue
+				}
+			}
+		}
+		var s historySample
+		if err := json.Unmarshal(line, &s); err != nil || s.Time.Before(since) {
+			continue
+		}
+		if seen[s.key()] {
+			continue
+		}
+		seen[s.key()] = true
+		*out = append(*out, s)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+	return nil
+}
+
+// runRecord implements the record subcommand: it polls the rate limits of
+// every enabled provider and appends them to the history until
+// interrupted. It returns the process exit code.
+func runRecord(cfg Config, args []string) int {
+	fs := flag.NewFlagSet("record", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: llm-usage record [--interval DURATION] [--once] [--backfill]")
+		fs.PrintDefaults()
+	}
+	interval := fs.Duration("interval", 5*time.Minute, "time between polls")
+	once := fs.Bool("once", false, "poll once and exit")
+	backfill := fs.Bool("backfill", false, "first import the limits providers kept locally (Codex sessions)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *interval < time.Minute {
+		fmt.Fprintln(os.Stderr, "error: --interval must be at least 1m")
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	enabled := enabledProviders(cfg)
+	if *backfill {
+		n, err := backfillHistory(ctx, enabled)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: backfill: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "backfilled %d samples\n", n)
+	}
+
+	for {
+		var wg sync.WaitGroup
+		for _, p := range enabled {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				limits, err := p.FetchLimits(ctx)
+				if err == nil && limits != nil {
+					err = recordLimits(p.Name(), limits)
+				}
+				if err != nil && ctx.Err() == nil {
+					fmt.Fprintf(os.Stderr, "%s: %v\n", p.Name(), err)
+				}
+			}()
+		}
+		wg.Wait()
+		if *once {
+			return 0
+		}
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-time.After(*interval):
+		}
+	}
+}
+
+// backfillHistory appends the past limits the providers kept locally and
+// that aren't in the history yet. It returns how many samples it added.
+func backfillHistory(ctx context.Context, ps []Provider) (int, error) {
+	recorded, err := readHistory(time.Time{})
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[historyKey]bool, len(recorded))
+	for _, s := range recorded {
+		seen[s.key()] = true
+	}
+
+	var added []historySample
+	for _, p := range ps {
+		hp, ok := p.(historyProvider)
+		if !ok {
+			continue
+		}
+		samples, err := hp.LimitHistory(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", p.Name(), err)
+		}
+		for _, s := range samples {
+			if !seen[s.key()] {
+				seen[s.key()] = true
+				added = append(added, s)
+			}
+		}
+	}
+	return len(added), appendHistory(added)
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// scanIndexVersion is bumped whenever the meaning of cached records
+// changes; an index written by another version is discarded.
+const scanIndexVersion = 3
+
+// usageRecord is one usage observation parsed from a session file. What
+// Tokens holds depends on the provider: per-message usage for Claude,
+// cumulative session totals for Codex and Kimi.
+type usageRecord struct {
+	Time   int64  // unix nanoseconds; 0 if the entry had no timestamp
+	ID     string // message and request ID for deduplication, if any
+	Key    int    // index into fileState.Keys
+	Tokens TokenStats
+}
+
+// fileState is the cached parse state of one session file.
+type fileState struct {
+	Size    int64
+	ModTime time.Time
+	Offset  int64  // end of the last complete line parsed
+	Head    uint64 // hash of the first line, to detect replaced files
+
+	Keys    []UsageKey     // interned model/project pairs
+	Records []usageRecord  // in file order
+	Seen    map[string]int // record ID -> index into Records
+	First   int64          // timestamp of the first entry, unix nanoseconds
+
+	// Codex parse state carried over to appended bytes.
+	Model string
+	Cwd   string
+}
+
+// add appends a record, replacing an earlier one with the same ID.
+func (st *fileState) add(t time.Time, id string, key UsageKey, tokens TokenStats) {
+	r := usageRecord{ID: id, Key: st.intern(key), Tokens: tokens}
+	if !t.IsZero() {
+		r.Time = t.UnixNano()
+	}
+	if id != "" {
+		if i, ok := st.Seen[id]; ok {
+			st.Records[i] = r
+			return
+		}
+		if st.Seen == nil {
+			st.Seen = make(map[string]int)
+		}
+		st.Seen[id] = len(st.Records)
+	}
+	st.Records = append(st.Records, r)
+}
+
+func (st *fileState) intern(key UsageKey) int {
+	for i, k := range st.Keys {
+		if k == key {
+			return i
+		}
+	}
+	st.Keys = append(st.Keys, key)
+	return len(st.Keys) - 1
+}
+
+// recordTime returns a record's timestamp, or the zero time if it had none.
+func recordTime(r usageRecord) time.Time {
+	if r.Time == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, r.Time)
+}
+
+// lineParser parses one complete JSONL line into a file's state.
+type lineParser func(st *fileState, line []byte)
+
+// scanIndex caches the parse state of every session file seen, so a
+// rescan only reads the bytes appended since the previous one.
+type scanIndex struct {
+	Version int
+	Files   map[string]*fileState
+
+	mu     sync.Mutex
+	loaded bool
+	dirty  bool
+}
+
+var index = &scanIndex{}
+
+// cacheDir returns the cache directory.
+func cacheDir() string {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "llm-usage")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".cache", "llm-usage")
+}
+
+// indexPath returns the full path to the scan index.
+func indexPath() string {
+	return filepath.Join(cacheDir(), "scan-index.gob")
+}
+
+// load reads the index from disk on first use. A missing, unreadable or
+// outdated index just starts empty. Must be called with mu held.
+func (x *scanIndex) load() {
+	if x.loaded {
+		return
+	}
+	x.loaded = true
+	x.Version = scanIndexVersion
+	x.Files = make(map[string]*fileState)
+
+	f, err := os.Open(indexPath())
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var disk struct {
+		Version int
+		Files   map[string]*fileState
+	}
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(&disk); err != nil {
+		return
+	}
+	if disk.Version == scanIndexVersion && disk.Files != nil {
+		x.Files = disk.Files
+	}
+}
+
+// withFile brings the cached state of path up to date and calls fn with
+// it. It is safe for concurrent use: cached states are never modified in
+// place, so an update parses into a copy that replaces the cached one.
+// The state must not be modified by fn or retained after fn returns. fn
+// isn't called if the file can't be read or ctx is cancelled mid-parse.
+func (x *scanIndex) withFile(ctx context.Context, path string, parse lineParser, fn func(st *fileState)) {
+	x.mu.Lock()
+	x.load()
+	cached := x.Files[path]
+	x.mu.Unlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		x.mu.Lock()
+		delete(x.Files, path)
+		x.mu.Unlock()
+		return
+	}
+
+	st := cached
+	if st == nil || info.Size() < st.Offset || (info.Size() == st.Size && !info.ModTime().Equal(st.ModTime)) {
+		// new, truncated or rewritten in place: start over
+		st = &fileState{}
+	}
+	if info.Size() != st.Size || !info.ModTime().Equal(st.ModTime) {
+		if st == cached {
+			st = cached.clone()
+		}
+		if err := st.update(ctx, path, parse); err != nil {
+			return
+		}
+		st.Size = info.Size()
+		st.ModTime = info.ModTime()
+		x.mu.Lock()
+		x.Files[path] = st
+		x.dirty = true
+		x.mu.Unlock()
+	}
+	fn(st)
+}
+
+// clone returns a copy of st that can be updated without affecting st.
+func (st *fileState) clone() *fileState {
+	c := *st
+	c.Keys = slices.Clone(st.Keys)
+	c.Records = slices.Clone(st.Records)
+	c.Seen = maps.Clone(st.Seen)
+	return &c
+}
+
+// update parses the complete lines appended to path since st.Offset. If
+// the file's first line no longer matches, it was replaced and is re-read.
+// A cancelled ctx stops the parse with ctx.Err(), leaving st incomplete.
+func (st *fileState) update(ctx context.Context, path string, parse lineParser) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReaderSize(f, 64*1024)
+	head, err := r.ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if len(head) == 0 || head[len(head)-1] != '\n' {
+		return nil // no complete line yet
+	}
+	h := fnv.New64a()
+	h.Write(head)
+	if st.Offset > 0 && h.Sum64() != st.Head {
+		*st = fileState{}
+	}
+	st.Head = h.Sum64()
+
+	if st.Offset == 0 {
+		parse(st, head)
+		st.Offset = int64(len(head))
+	} else {
+		if _, err := f.Seek(st.Offset, io.SeekStart); err != nil {
+			return err
+		}
+		r.Reset(f)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			parse(st, line)
+			st.Offset += int64(len(line))
+		}
+		if errors.Is(err, io.EOF) {
+			return nil // a trailing partial line is picked up next time
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// flush writes the index to disk if it changed, dropping files that no
+// longer exist. It writes to a temp file and renames it into place so
+// concurrent llm-usage processes never read a partial index.
+func (x *scanIndex) flush() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !x.dirty {
+		return nil
+	}
+	for path := range x.Files {
+		if _, err := os.Stat(path); err != nil {
+			delete(x.Files, path)
+		}
+	}
+
+	dir := cacheDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, "scan-index-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write scan index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	disk := struct {
+		Version int
+		Files   map[string]*fileState
+	}{x.Version, x.Files}
+	if err := gob.NewEncoder(w).Encode(disk); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode scan index: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write scan index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write scan index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), indexPath()); err != nil {
+		return fmt.Errorf("failed to write scan index: %w", err)
+	}
+	x.dirty = false
+	return nil
+}
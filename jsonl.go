@@ -1,23 +1,26 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 )
 
 type TokenStats struct {
 	InputTokens   int
-	OutputTokens  int
+	OutputTokens  int // excluding Reasoning
 	CacheCreation int
 	CacheRead     int
+	Reasoning     int // hidden reasoning output; only Codex reports it separately
 }
 
 func (t TokenStats) Total() int {
-	return t.InputTokens + t.OutputTokens + t.CacheCreation + t.CacheRead
+	return t.InputTokens + t.OutputTokens + t.CacheCreation + t.CacheRead + t.Reasoning
 }
 
 // Add returns the sum of two TokenStats.
@@ -27,269 +30,309 @@ func (t TokenStats) Add(other TokenStats) TokenStats {
 		OutputTokens:  t.OutputTokens + other.OutputTokens,
 		CacheCreation: t.CacheCreation + other.CacheCreation,
 		CacheRead:     t.CacheRead + other.CacheRead,
+		Reasoning:     t.Reasoning + other.Reasoning,
 	}
 }
 
-// DailyTokenStats maps day-of-month (1-31) to TokenStats.
-type DailyTokenStats map[int]TokenStats
-
-func claudeSessionDirs() []string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return nil
-	}
-	candidates := []string{
-		filepath.Join(home, ".claude", "projects"),
-		filepath.Join(home, ".config", "claude", "projects"),
+// Sub returns t minus other.
+func (t TokenStats) Sub(other TokenStats) TokenStats {
+	return TokenStats{
+		InputTokens:   t.InputTokens - other.InputTokens,
+		OutputTokens:  t.OutputTokens - other.OutputTokens,
+		CacheCreation: t.CacheCreation - other.CacheCreation,
+		CacheRead:     t.CacheRead - other.CacheRead,
+		Reasoning:     t.Reasoning - other.Reasoning,
 	}
-	var dirs []string
-	for _, d := range candidates {
-		if info, err := os.Stat(d); err == nil && info.IsDir() {
-			dirs = append(dirs, d)
-		}
+}
+
+// ModelTokenStats maps a model name to its TokenStats. Usage whose model
+// is unknown is keyed by the empty string.
+type ModelTokenStats map[string]TokenStats
+
+// Sum returns the combined TokenStats of every model.
+func (m ModelTokenStats) Sum() TokenStats {
+	var total TokenStats
+	for _, s := range m {
+		total = total.Add(s)
 	}
-	return dirs
+	return total
 }
 
-func scanClaudeTokens(since time.Time) (TokenStats, error) {
-	var stats TokenStats
-	dirs := claudeSessionDirs()
-	if len(dirs) == 0 {
-		return stats, nil
+// Merge adds every model of other into m.
+func (m ModelTokenStats) Merge(other ModelTokenStats) {
+	for model, s := range other {
+		m[model] = m[model].Add(s)
 	}
+}
 
-	for _, root := range dirs {
-		err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
-			if err != nil {
-				return nil // skip inaccessible dirs
-			}
-			if d.IsDir() {
-				return nil
-			}
-			if filepath.Ext(path) != ".jsonl" {
-				return nil
-			}
-			// Quick filter: skip files last modified before our window
-			if info, err := d.Info(); err == nil && info.ModTime().Before(since) {
-				return nil
-			}
-			scanClaudeFileTokens(path, since, &stats)
-			return nil
-		})
-		if err != nil {
-			continue
-		}
+// Sorted returns the model names ordered by descending total tokens.
+func (m ModelTokenStats) Sorted() []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
 	}
-	return stats, nil
+	sort.Slice(names, func(i, j int) bool {
+		ti, tj := m[names[i]].Total(), m[names[j]].Total()
+		if ti != tj {
+			return ti > tj
+		}
+		return names[i] < names[j]
+	})
+	return names
 }
 
-// scanAllTokens combines Claude + Codex + Kimi token counts.
-func scanAllTokens(since time.Time) (TokenStats, error) {
-	claude, err := scanClaudeTokens(since)
-	if err != nil {
-		return claude, err
+// UsageKey identifies the model and project a slice of token usage
+// belongs to. Either field is empty when unknown.
+type UsageKey struct {
+	Model   string
+	Project string
+}
+
+// TokenBreakdown maps usage keys to TokenStats.
+type TokenBreakdown map[UsageKey]TokenStats
+
+// Add adds s to the given key.
+func (b TokenBreakdown) Add(key UsageKey, s TokenStats) {
+	b[key] = b[key].Add(s)
+}
+
+// Sub removes s from the given key, dropping the key once it is empty.
+func (b TokenBreakdown) Sub(key UsageKey, s TokenStats) {
+	if left := b[key].Sub(s); left != (TokenStats{}) {
+		b[key] = left
+	} else {
+		delete(b, key)
 	}
-	codex, err := scanCodexTokens(since)
-	if err != nil {
-		return claude, err
+}
+
+// Merge adds every key of other into b.
+func (b TokenBreakdown) Merge(other TokenBreakdown) {
+	for key, s := range other {
+		b.Add(key, s)
 	}
-	kimi, err := scanKimiTokens(since)
-	if err != nil {
-		return claude, err
+}
+
+// Sum returns the combined TokenStats of every key.
+func (b TokenBreakdown) Sum() TokenStats {
+	var total TokenStats
+	for _, s := range b {
+		total = total.Add(s)
 	}
-	return TokenStats{
-		InputTokens:   claude.InputTokens + codex.InputTokens + kimi.InputTokens,
-		OutputTokens:  claude.OutputTokens + codex.OutputTokens + kimi.OutputTokens,
-		CacheCreation: claude.CacheCreation + codex.CacheCreation + kimi.CacheCreation,
-		CacheRead:     claude.CacheRead + codex.CacheRead + kimi.CacheRead,
-	}, nil
+	return total
 }
 
-func scanClaudeFileTokens(path string, since time.Time, stats *TokenStats) {
-	f, err := os.Open(path)
-	if err != nil {
-		return
+// ByModel collapses the breakdown to per-model totals.
+func (b TokenBreakdown) ByModel() ModelTokenStats {
+	out := make(ModelTokenStats)
+	for key, s := range b {
+		out[key.Model] = out[key.Model].Add(s)
 	}
-	defer f.Close()
+	return out
+}
 
-	// Claude Code writes multiple JSONL entries per streamed message (same
-	// message ID, cumulative usage). We must deduplicate: keep only the last
-	// entry per message ID which holds the final token counts.
-	type usage struct {
-		in, out, cacheCreate, cacheRead int
-	}
-	seen := make(map[string]usage)  // message ID -> final usage
-	var anonymous []usage           // entries without a message ID
-
-	scanner := bufio.NewScanner(f)
-	scanner.Buffer(make([]byte, 512*1024), 512*1024)
-	for scanner.Scan() {
-		line := scanner.Bytes()
-
-		var entry jsonlEntry
-		if err := json.Unmarshal(line, &entry); err != nil {
-			continue
-		}
-		if entry.Type != "assistant" {
-			continue
-		}
-		if entry.Message == nil || entry.Message.Usage == nil {
-			continue
+// ByProject collapses the breakdown to per-project, per-model totals.
+func (b TokenBreakdown) ByProject() ProjectTokenStats {
+	out := make(ProjectTokenStats)
+	for key, s := range b {
+		if out[key.Project] == nil {
+			out[key.Project] = make(ModelTokenStats)
 		}
+		out[key.Project][key.Model] = out[key.Project][key.Model].Add(s)
+	}
+	return out
+}
 
-		ts, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
-		if err != nil {
-			continue
-		}
-		if ts.Before(since) {
-			continue
-		}
+// ProjectTokenStats maps a project path to its per-model TokenStats.
+type ProjectTokenStats map[string]ModelTokenStats
 
-		u := usage{
-			in:          entry.Message.Usage.InputTokens,
-			out:         entry.Message.Usage.OutputTokens,
-			cacheCreate: entry.Message.Usage.CacheCreationInputTokens,
-			cacheRead:   entry.Message.Usage.CacheReadInputTokens,
+// Sorted returns the project paths ordered by descending total tokens.
+func (p ProjectTokenStats) Sorted() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ti, tj := p[names[i]].Sum().Total(), p[names[j]].Sum().Total()
+		if ti != tj {
+			return ti > tj
 		}
+		return names[i] < names[j]
+	})
+	return names
+}
 
-		if entry.Message.ID != "" {
-			seen[entry.Message.ID] = u // last write wins
-		} else {
-			anonymous = append(anonymous, u)
-		}
+// DailyTokenStats maps day-of-month (1-31) to per-model TokenStats.
+type DailyTokenStats map[int]ModelTokenStats
+
+// Add adds s to the given day and model.
+func (d DailyTokenStats) Add(day int, model string, s TokenStats) {
+	if d[day] == nil {
+		d[day] = make(ModelTokenStats)
 	}
+	d[day][model] = d[day][model].Add(s)
+}
 
-	for _, u := range seen {
-		stats.InputTokens += u.in
-		stats.OutputTokens += u.out
-		stats.CacheCreation += u.cacheCreate
-		stats.CacheRead += u.cacheRead
+// Sub removes s from the given day and model, dropping entries once they
+// are empty.
+func (d DailyTokenStats) Sub(day int, model string, s TokenStats) {
+	models := d[day]
+	if models == nil {
+		return
+	}
+	if left := models[model].Sub(s); left != (TokenStats{}) {
+		models[model] = left
+	} else {
+		delete(models, model)
 	}
-	for _, u := range anonymous {
-		stats.InputTokens += u.in
-		stats.OutputTokens += u.out
-		stats.CacheCreation += u.cacheCreate
-		stats.CacheRead += u.cacheRead
+	if len(models) == 0 {
+		delete(d, day)
 	}
 }
 
-// scanClaudeTokensByDay scans Claude JSONL files and buckets token usage by day of month.
-func scanClaudeTokensByDay(year int, month time.Month) (DailyTokenStats, error) {
-	daily := make(DailyTokenStats)
-	dirs := claudeSessionDirs()
-	if len(dirs) == 0 {
-		return daily, nil
+// Merge adds every day of other into d.
+func (d DailyTokenStats) Merge(other DailyTokenStats) {
+	for day, models := range other {
+		for model, s := range models {
+			d.Add(day, model, s)
+		}
 	}
+}
 
-	loc := time.Now().Location()
-	since := time.Date(year, month, 1, 0, 0, 0, 0, loc)
-	until := since.AddDate(0, 1, 0)
+// claudeSessionDirs returns the session directories of the Claude Code
+// config dir configDir, or of the default one if configDir is empty.
+func claudeSessionDirs(configDir string) []string {
+	if configDir != "" || os.Getenv("CLAUDE_CONFIG_DIR") != "" {
+		return existingDirs(filepath.Join(claudeConfigDir(configDir), "projects"))
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return existingDirs(
+		filepath.Join(home, ".claude", "projects"),
+		filepath.Join(home, ".config", "claude", "projects"),
+	)
+}
 
+// scanClaudeUsage emits, as the given provider, the usage of every Claude
+// message in dirs since the given time.
+func scanClaudeUsage(ctx context.Context, provider string, dirs []string, since time.Time, emit func(UsageEvent)) error {
+	files, err := claudeFiles(ctx, dirs, since)
+	if err != nil {
+		return err
+	}
+	return scanFiles(ctx, provider, files, claudeUsage, since, emit)
+}
+
+// claudeFiles lists the Claude session files in dirs modified since the
+// given time.
+func claudeFiles(ctx context.Context, dirs []string, since time.Time) ([]sessionFile, error) {
+	var files []sessionFile
 	for _, root := range dirs {
-		filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
-			if err != nil || d.IsDir() || filepath.Ext(path) != ".jsonl" {
+		err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				return nil // skip inaccessible dirs
+			}
+			if d.IsDir() {
+				return nil
+			}
+			if filepath.Ext(path) != ".jsonl" {
 				return nil
 			}
+			// Quick filter: skip files last modified before our window
 			if info, err := d.Info(); err == nil && info.ModTime().Before(since) {
 				return nil
 			}
-			scanClaudeFileTokensByDay(path, since, until, daily)
+			files = append(files, claudeSessionFile(root, path))
 			return nil
 		})
+		if err != nil {
+			return nil, err
+		}
 	}
-	return daily, nil
+	return files, nil
 }
 
-func scanClaudeFileTokensByDay(path string, since, until time.Time, daily DailyTokenStats) {
-	f, err := os.Open(path)
+// claudeSessionFile describes the session file at path under the projects
+// directory root.
+func claudeSessionFile(root, path string) sessionFile {
+	fallback := claudeProjectFallback(root, path)
+	return sessionFile{
+		path: path,
+		id:   strings.TrimSuffix(filepath.Base(path), ".jsonl"),
+		parse: func(st *fileState, line []byte) {
+			parseClaudeLine(st, line, fallback)
+		},
+	}
+}
+
+// parseClaudeLine records the usage of an assistant entry. Entries without
+// a cwd are attributed to fallbackProject.
+func parseClaudeLine(st *fileState, line []byte, fallbackProject string) {
+	var entry jsonlEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return
+	}
+	ts, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
 	if err != nil {
 		return
 	}
-	defer f.Close()
-
-	type usage struct {
-		in, out, cacheCreate, cacheRead int
-		day                             int
+	if st.First == 0 {
+		st.First = ts.UnixNano()
 	}
-	seen := make(map[string]usage)
-	var anonymous []usage
-
-	scanner := bufio.NewScanner(f)
-	scanner.Buffer(make([]byte, 512*1024), 512*1024)
-	for scanner.Scan() {
-		var entry jsonlEntry
-		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
-			continue
-		}
-		if entry.Type != "assistant" || entry.Message == nil || entry.Message.Usage == nil {
-			continue
-		}
-		ts, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
-		if err != nil || ts.Before(since) || !ts.Before(until) {
-			continue
-		}
-
-		u := usage{
-			in:          entry.Message.Usage.InputTokens,
-			out:         entry.Message.Usage.OutputTokens,
-			cacheCreate: entry.Message.Usage.CacheCreationInputTokens,
-			cacheRead:   entry.Message.Usage.CacheReadInputTokens,
-			day:         ts.Day(),
-		}
-		if entry.Message.ID != "" {
-			seen[entry.Message.ID] = u
-		} else {
-			anonymous = append(anonymous, u)
-		}
+	if entry.Type != "assistant" {
+		return
+	}
+	if entry.Message == nil || entry.Message.Usage == nil {
+		return
 	}
 
-	add := func(u usage) {
-		s := daily[u.day]
-		s.InputTokens += u.in
-		s.OutputTokens += u.out
-		s.CacheCreation += u.cacheCreate
-		s.CacheRead += u.cacheRead
-		daily[u.day] = s
+	project := fallbackProject
+	if entry.Cwd != "" {
+		project = projectRoot(entry.Cwd)
 	}
-	for _, u := range seen {
-		add(u)
+
+	// Claude Code writes multiple JSONL entries per streamed message (same
+	// message ID, cumulative usage). We must deduplicate: st.add keeps only
+	// the last entry per message, which holds the final token counts. The
+	// same key identifies copies of the message in other session files.
+	id := ""
+	if entry.Message.ID != "" {
+		id = entry.Message.ID + ":" + entry.RequestID
 	}
-	for _, u := range anonymous {
-		add(u)
+	st.add(ts, id, UsageKey{Model: entry.Message.Model, Project: project}, TokenStats{
+		InputTokens:   entry.Message.Usage.InputTokens,
+		OutputTokens:  entry.Message.Usage.OutputTokens,
+		CacheCreation: entry.Message.Usage.CacheCreationInputTokens,
+		CacheRead:     entry.Message.Usage.CacheReadInputTokens,
+	})
+}
+
+// claudeUsage reports every deduplicated message since the given time.
+func claudeUsage(st *fileState, since time.Time, emit func(time.Time, string, UsageKey, TokenStats)) {
+	for _, r := range st.Records {
+		if t := recordTime(r); !t.Before(since) {
+			emit(t, r.ID, st.Keys[r.Key], r.Tokens)
+		}
 	}
 }
 
-// scanAllTokensByDay combines Claude + Codex + Kimi per-day token counts.
-func scanAllTokensByDay(year int, month time.Month) (DailyTokenStats, error) {
-	claude, err := scanClaudeTokensByDay(year, month)
-	if err != nil {
-		return claude, err
+// shortModelName trims vendor prefixes and date suffixes from a model ID
+// for display, e.g. "claude-opus-4-1-20250805" becomes "opus-4-1".
+func shortModelName(name string) string {
+	if name == "" {
+		return "unknown"
 	}
-	codex, err := scanCodexTokensByDay(year, month)
-	if err != nil {
-		return claude, err
+	name = strings.TrimPrefix(name, "claude-")
+	if i := strings.LastIndexByte(name, '-'); i >= 0 && len(name)-i-1 == 8 {
+		if _, err := time.Parse("20060102", name[i+1:]); err == nil {
+			name = name[:i]
+		}
 	}
-	kimi, err := scanKimiTokensByDay(year, month)
-	if err != nil {
-		return claude, err
-	}
-	for day, cs := range codex {
-		s := claude[day]
-		s.InputTokens += cs.InputTokens
-		s.OutputTokens += cs.OutputTokens
-		s.CacheCreation += cs.CacheCreation
-		s.CacheRead += cs.CacheRead
-		claude[day] = s
-	}
-	for day, ks := range kimi {
-		s := claude[day]
-		s.InputTokens += ks.InputTokens
-		s.OutputTokens += ks.OutputTokens
-		s.CacheCreation += ks.CacheCreation
-		s.CacheRead += ks.CacheRead
-		claude[day] = s
-	}
-	return claude, nil
+	return name
 }
 
 func formatTokenCount(n int) string {
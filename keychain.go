@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -12,14 +13,154 @@ import (
 	"time"
 )
 
-func loadToken() (string, string, error) {
-	// env var override first
-	if tok := os.Getenv("CLAUDE_OAUTH_TOKEN"); tok != "" {
-		return tok, "", nil
+// credentialSource is one place Claude Code's OAuth credentials may be
+// stored.
+type credentialSource struct {
+	name string
+	load func() (OAuthEntry, error)
+	// save writes refreshed credentials back, so Claude Code keeps a
+	// working refresh token; nil if the source can't be written.
+	save func(OAuthEntry) error
+}
+
+// credentialSources returns the places to look for the provider's
+// credentials, in order: the environment, Claude Code's credentials file,
+// the macOS Keychain and the user's credentials command. A profile only
+// has its own credentials file and command.
+func (p *claudeProvider) credentialSources() []credentialSource {
+	path := claudeCredentialsPath(p.profile.ConfigDir)
+	file := credentialSource{
+		name: path,
+		load: func() (OAuthEntry, error) { return loadCredentialsFile(path) },
+		save: func(creds OAuthEntry) error { return saveCredentialsFile(path, creds) },
+	}
+	command := credentialSource{name: "credentials_command", load: func() (OAuthEntry, error) {
+		return loadCommandToken(p.profile.CredentialsCommand)
+	}}
+	if p.profile.Name != "" {
+		return []credentialSource{file, command}
+	}
+	return []credentialSource{
+		{name: "CLAUDE_OAUTH_TOKEN", load: loadEnvToken},
+		file,
+		{name: "Keychain", load: loadKeychainToken},
+		command,
+	}
+}
+
+// loadToken returns the Claude OAuth credentials from the first source that
+// has them, and that source. A token from the environment or a command
+// that prints a bare token comes without a refresh token or expiry.
+func (p *claudeProvider) loadToken() (OAuthEntry, credentialSource, error) {
+	var tried []string
+	for _, src := range p.credentialSources() {
+		creds, err := src.load()
+		if err == nil {
+			return creds, src, nil
+		}
+		tried = append(tried, src.name+": "+err.Error())
+	}
+	return OAuthEntry{}, credentialSource{}, fmt.Errorf("no Claude Code credentials found (tried %s)", strings.Join(tried, "; "))
+}
+
+func loadEnvToken() (OAuthEntry, error) {
+	tok := os.Getenv("CLAUDE_OAUTH_TOKEN")
+	if tok == "" {
+		return OAuthEntry{}, fmt.Errorf("not set")
+	}
+	return OAuthEntry{AccessToken: tok}, nil
+}
+
+// claudeConfigDir returns the Claude Code config dir dir with a leading
+// "~" expanded or, if dir is empty, the default one: $CLAUDE_CONFIG_DIR,
+// or ~/.claude.
+func claudeConfigDir(dir string) string {
+	if dir == "" {
+		dir = os.Getenv("CLAUDE_CONFIG_DIR")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = ""
+	}
+	if dir == "" {
+		return filepath.Join(home, ".claude")
+	}
+	if rest, ok := strings.CutPrefix(dir, "~"); ok && (rest == "" || rest[0] == '/') {
+		return filepath.Join(home, rest)
+	}
+	return dir
+}
+
+// claudeCredentialsPath returns the file Claude Code stores its
+// credentials in on Linux: .credentials.json in its config dir.
+func claudeCredentialsPath(configDir string) string {
+	return filepath.Join(claudeConfigDir(configDir), ".credentials.json")
+}
+
+func loadCredentialsFile(path string) (OAuthEntry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return OAuthEntry{}, fmt.Errorf("not found")
+	}
+	if err != nil {
+		return OAuthEntry{}, err
+	}
+	return parseCredentials(data)
+}
+
+// saveCredentialsFile replaces the OAuth tokens in Claude Code's
+// credentials file with creds, keeping every other field as it is. It
+// writes to a temp file and renames it into place, so Claude Code never
+// reads a partial file.
+func saveCredentialsFile(path string, creds OAuthEntry) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var file map[string]json.RawMessage
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse credentials: %w", err)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal(file["claudeAiOauth"], &entry); err != nil || entry == nil {
+		return fmt.Errorf("no OAuth token in credentials")
+	}
+	entry["accessToken"] = creds.AccessToken
+	entry["refreshToken"] = creds.RefreshToken
+	entry["expiresAt"] = creds.ExpiresAt
+	if file["claudeAiOauth"], err = json.Marshal(entry); err != nil {
+		return err
+	}
+	if data, err = json.Marshal(file); err != nil {
+		return err
 	}
 
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".credentials-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func loadKeychainToken() (OAuthEntry, error) {
 	if runtime.GOOS != "darwin" {
-		return "", "", fmt.Errorf("CLAUDE_OAUTH_TOKEN must be set (Keychain auto-detection is macOS-only)")
+		return OAuthEntry{}, fmt.Errorf("macOS only")
 	}
 
 	securityPath := "/usr/bin/security"
@@ -40,17 +181,44 @@ func loadToken() (string, string, error) {
 		"-w",
 	).Output()
 	if err != nil {
-		return "", "", fmt.Errorf("no Claude Code credentials found in Keychain")
+		return OAuthEntry{}, fmt.Errorf("no Claude Code-credentials entry")
 	}
+	return parseCredentials(out)
+}
 
-	var creds KeychainCredentials
-	if err := json.Unmarshal([]byte(strings.TrimSpace(string(out))), &creds); err != nil {
-		return "", "", fmt.Errorf("failed to parse Keychain credentials: %w", err)
+// loadCommandToken runs the user's credentials command, which prints
+// either Claude Code's credentials JSON or a bare access token.
+func loadCommandToken(command []string) (OAuthEntry, error) {
+	if len(command) == 0 {
+		return OAuthEntry{}, fmt.Errorf("not configured")
 	}
 
-	if creds.ClaudeAiOauth == nil || creds.ClaudeAiOauth.AccessToken == "" {
-		return "", "", fmt.Errorf("no OAuth token in Keychain credentials")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, command[0], command[1:]...).Output()
+	if err != nil {
+		return OAuthEntry{}, fmt.Errorf("%s failed: %w", command[0], err)
 	}
+	out = []byte(strings.TrimSpace(string(out)))
+	if len(out) > 0 && out[0] == '{' {
+		return parseCredentials(out)
+	}
+	if len(out) == 0 || strings.ContainsAny(string(out), " \t\n") {
+		return OAuthEntry{}, fmt.Errorf("%s printed no token", command[0])
+	}
+	return OAuthEntry{AccessToken: string(out)}, nil
+}
 
-	return creds.ClaudeAiOauth.AccessToken, creds.ClaudeAiOauth.SubscriptionType, nil
+// parseCredentials parses Claude Code's stored credentials, the same JSON
+// in the credentials file and the Keychain.
+func parseCredentials(data []byte) (OAuthEntry, error) {
+	var creds KeychainCredentials
+	if err := json.Unmarshal([]byte(strings.TrimSpace(string(data))), &creds); err != nil {
+		return OAuthEntry{}, fmt.Errorf("failed to parse credentials: %w", err)
+	}
+	if creds.ClaudeAiOauth == nil || creds.ClaudeAiOauth.AccessToken == "" {
+		return OAuthEntry{}, fmt.Errorf("no OAuth token in credentials")
+	}
+	return *creds.ClaudeAiOauth, nil
 }
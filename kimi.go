@@ -1,7 +1,7 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -15,6 +15,36 @@ type KimiUsage struct {
 	// Rate limits would need to be fetched from API if available.
 }
 
+func init() {
+	registerProvider(kimiProvider{})
+}
+
+// kimiProvider reports token counts from Kimi CLI session files. Kimi
+// doesn't store rate limits locally, so FetchLimits always returns nil.
+type kimiProvider struct{}
+
+func (kimiProvider) Name() string  { return "kimi" }
+func (kimiProvider) Title() string { return "Kimi" }
+
+func (kimiProvider) FetchLimits(ctx context.Context) (*RateLimits, error) {
+	return nil, nil
+}
+
+func (kimiProvider) ScanUsage(ctx context.Context, since time.Time, emit func(UsageEvent)) error {
+	return scanKimiUsage(ctx, since, emit)
+}
+
+func (kimiProvider) WatchDirs() []string {
+	return existingDirs(kimiSessionDir())
+}
+
+func (kimiProvider) SessionFile(path string) (sessionFile, usageFunc, bool) {
+	if filepath.Base(path) != "wire.jsonl" || !withinDir(kimiSessionDir(), path) {
+		return sessionFile{}, nil, false
+	}
+	return kimiSessionFile(path), kimiUsage, true
+}
+
 // kimiSessionDir returns the Kimi sessions directory.
 func kimiSessionDir() string {
 	if home := os.Getenv("KIMI_HOME"); home != "" {
@@ -46,18 +76,33 @@ type kimiTokenUsage struct {
 	InputCacheCreation int `json:"input_cache_creation"`
 }
 
-// scanKimiTokens scans Kimi session files for token usage since the given time.
-func scanKimiTokens(since time.Time) (TokenStats, error) {
-	var stats TokenStats
+// scanKimiUsage emits the usage of every Kimi session since the given time.
+// Kimi's wire files don't record the model or working directory, so usage
+// is keyed by an empty UsageKey.
+func scanKimiUsage(ctx context.Context, since time.Time, emit func(UsageEvent)) error {
+	files, err := kimiFiles(ctx, since)
+	if err != nil {
+		return err
+	}
+	return scanFiles(ctx, "kimi", files, kimiUsage, since, emit)
+}
+
+// kimiFiles lists the wire.jsonl files modified since the given time. The
+// session ID is the name of the directory holding the wire file.
+func kimiFiles(ctx context.Context, since time.Time) ([]sessionFile, error) {
 	dir := kimiSessionDir()
 	if dir == "" {
-		return stats, fmt.Errorf("kimi sessions directory not found")
+		return nil, fmt.Errorf("kimi sessions directory not found")
 	}
 	if _, err := os.Stat(dir); err != nil {
-		return stats, nil // not installed, return zeros
+		return nil, nil // not installed
 	}
 
+	var files []sessionFile
 	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		if err != nil {
 			return nil
 		}
@@ -72,151 +117,59 @@ func scanKimiTokens(since time.Time) (TokenStats, error) {
 		if info, err := d.Info(); err == nil && info.ModTime().Before(since) {
 			return nil
 		}
-		scanKimiWireFile(path, since, &stats)
+		files = append(files, kimiSessionFile(path))
 		return nil
 	})
-	if err != nil {
-		return stats, err
+	return files, err
+}
+
+// kimiSessionFile describes the wire file at path.
+func kimiSessionFile(path string) sessionFile {
+	return sessionFile{
+		path:  path,
+		id:    filepath.Base(filepath.Dir(path)),
+		parse: parseKimiLine,
 	}
-	return stats, nil
 }
 
-// scanKimiWireFile reads a single Kimi wire.jsonl file and adds its token usage.
-// Uses the last StatusUpdate entry per message_id as the final token counts.
-func scanKimiWireFile(path string, since time.Time, stats *TokenStats) {
-	f, err := os.Open(path)
-	if err != nil {
+// parseKimiLine records the cumulative usage of every StatusUpdate entry.
+func parseKimiLine(st *fileState, line []byte) {
+	var entry kimiWireEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
 		return
 	}
-	defer f.Close()
-
-	// Track the last token usage for this file
-	var lastUsage *kimiTokenUsage
-	var lastTimestamp float64
-
-	scanner := bufio.NewScanner(f)
-	scanner.Buffer(make([]byte, 512*1024), 512*1024)
-	for scanner.Scan() {
-		var entry kimiWireEntry
-		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
-			continue
-		}
-		if entry.Message == nil || entry.Message.Type != "StatusUpdate" {
-			continue
-		}
-		if entry.Message.Payload == nil || entry.Message.Payload.TokenUsage == nil {
-			continue
-		}
-
-		// Check timestamp is within our window
-		if entry.Timestamp > 0 {
-			ts := time.Unix(int64(entry.Timestamp), int64((entry.Timestamp-float64(int64(entry.Timestamp)))*1e9))
-			if ts.Before(since) {
-				continue
-			}
+	var ts time.Time
+	if entry.Timestamp > 0 {
+		ts = time.Unix(int64(entry.Timestamp), int64((entry.Timestamp-float64(int64(entry.Timestamp)))*1e9))
+		if st.First == 0 {
+			st.First = ts.UnixNano()
 		}
-
-		lastUsage = entry.Message.Payload.TokenUsage
-		lastTimestamp = entry.Timestamp
 	}
-
-	if lastUsage == nil {
+	if entry.Message == nil || entry.Message.Type != "StatusUpdate" {
+		return
+	}
+	if entry.Message.Payload == nil || entry.Message.Payload.TokenUsage == nil {
 		return
 	}
 
 	// Kimi reports cumulative usage per session
 	// input_other = non-cached input tokens
-	// input_cache_read = cached input tokens  
+	// input_cache_read = cached input tokens
 	// input_cache_creation = cache creation tokens
 	// output = output tokens
-	stats.InputTokens += lastUsage.InputOther
-	stats.CacheRead += lastUsage.InputCacheRead
-	stats.CacheCreation += lastUsage.InputCacheCreation
-	stats.OutputTokens += lastUsage.Output
-
-	_ = lastTimestamp // avoid unused variable warning
-}
-
-// scanKimiTokensByDay scans Kimi session files and buckets token usage by day of month.
-func scanKimiTokensByDay(year int, month time.Month) (DailyTokenStats, error) {
-	daily := make(DailyTokenStats)
-	dir := kimiSessionDir()
-	if dir == "" {
-		return daily, nil
-	}
-	if _, err := os.Stat(dir); err != nil {
-		return daily, nil
-	}
-
-	loc := time.Now().Location()
-	since := time.Date(year, month, 1, 0, 0, 0, 0, loc)
-	until := since.AddDate(0, 1, 0)
-
-	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
-		if err != nil || d.IsDir() {
-			return nil
-		}
-		if filepath.Base(path) != "wire.jsonl" {
-			return nil
-		}
-		if info, err := d.Info(); err == nil && info.ModTime().Before(since) {
-			return nil
-		}
-		scanKimiWireFileByDay(path, since, until, daily)
-		return nil
+	u := entry.Message.Payload.TokenUsage
+	st.add(ts, "", UsageKey{}, TokenStats{
+		InputTokens:   u.InputOther,
+		CacheRead:     u.InputCacheRead,
+		CacheCreation: u.InputCacheCreation,
+		OutputTokens:  u.Output,
 	})
-	return daily, nil
 }
 
-// scanKimiWireFileByDay reads a single Kimi wire file and buckets usage by day.
-func scanKimiWireFileByDay(path string, since, until time.Time, daily DailyTokenStats) {
-	f, err := os.Open(path)
-	if err != nil {
-		return
-	}
-	defer f.Close()
-
-	var lastUsage *kimiTokenUsage
-	var lastDay int
-	var lastTS time.Time
-
-	scanner := bufio.NewScanner(f)
-	scanner.Buffer(make([]byte, 512*1024), 512*512*1024)
-	for scanner.Scan() {
-		var entry kimiWireEntry
-		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
-			continue
-		}
-		if entry.Message == nil || entry.Message.Type != "StatusUpdate" {
-			continue
-		}
-		if entry.Message.Payload == nil || entry.Message.Payload.TokenUsage == nil {
-			continue
-		}
-
-		if entry.Timestamp <= 0 {
-			continue
-		}
-		ts := time.Unix(int64(entry.Timestamp), int64((entry.Timestamp-float64(int64(entry.Timestamp)))*1e9))
-		if ts.Before(since) || !ts.Before(until) {
-			continue
-		}
-
-		lastUsage = entry.Message.Payload.TokenUsage
-		lastDay = ts.Day()
-		lastTS = ts
-	}
-
-	if lastUsage == nil {
-		return
-	}
-
-	s := daily[lastDay]
-	s.InputTokens += lastUsage.InputOther
-	s.CacheRead += lastUsage.InputCacheRead
-	s.CacheCreation += lastUsage.InputCacheCreation
-	s.OutputTokens += lastUsage.Output
-	daily[lastDay] = s
-
-	_ = lastTS
+// kimiUsage reports the growth of the session total between successive
+// StatusUpdates, each at the time of its own update, so a session that
+// started yesterday only counts today's tokens toward today. A total that
+// shrinks is taken as a reset of the session's counter.
+func kimiUsage(st *fileState, since time.Time, emit func(time.Time, string, UsageKey, TokenStats)) {
+	cumulativeUsage(st, since, emit)
 }
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"time"
+	"slices"
+	"strings"
+	"text/template"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -11,85 +14,202 @@ import (
 func main() {
 	// Load config (or use defaults)
 	cfg, _ := LoadConfig()
+	configureProviders(cfg)
 
-	// compact mode
-	if len(os.Args) > 1 && os.Args[1] == "--compact" {
-		runCompact(cfg)
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		os.Exit(runReport(cfg, os.Args[2:]))
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "record" {
+		os.Exit(runRecord(cfg, os.Args[2:]))
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "--json" {
+		runJSON(cfg)
 		return
 	}
 
-	token, subType, err := loadToken()
+	// compact mode; --output and --format imply it
+	if len(os.Args) > 1 {
+		switch flag, _, _ := strings.Cut(os.Args[1], "="); flag {
+		case "--compact", "--output", "--format":
+			runCompact(cfg, os.Args[1:])
+			return
+		}
+	}
+
+	for _, p := range enabledProviders(cfg) {
+		lp, ok := p.(loginProvider)
+		// a profile that can't log in shows the error in its own section,
+		// as FetchLimits logs in too
+		if !ok || profiles[p.Name()] {
+			continue
+		}
+		if err := lp.Login(); err != nil {
+			fmt.Fprintf(os.Stderr, " ✗ %s\n   Run \"%s\" and sign in first.\n", err, p.Name())
+			os.Exit(1)
+		}
+	}
+
+	p := tea.NewProgram(newModel(cfg), tea.WithAltScreen())
+	final, err := p.Run()
+	// keep what the watcher parsed since the last full scan
+	index.flush()
+	if m, ok := final.(model); ok && os.Getenv("LLM_USAGE_DEBUG") != "" {
+		fmt.Fprintf(os.Stderr, "debug: dropped %d duplicate messages\n", m.usage.Duplicates())
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, " ✗ %s\n   Run \"claude\" and sign in first.\n", err)
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
+}
 
-	p := tea.NewProgram(newModel(token, subType, cfg), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+// runJSON prints a snapshot of every enabled provider as JSON.
+func runJSON(cfg Config) {
+	if err := cachedSnapshot(context.Background(), cfg).writeJSON(os.Stdout); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func runCompact(cfg Config) {
-	token, _, err := loadToken()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+// runCompact prints a one-line summary. With --models, the 7-day token
+// total is followed by a per-model breakdown. --format replaces the whole
+// line with a template, given inline or by its name in config.json.
+// --output selects a status bar format; see writeStatusLine.
+func runCompact(cfg Config, args []string) {
+	byModel := false
+	output := "plain"
+	format, hasFormat := cfg.Formats["default"]
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == "--models":
+			byModel = true
+		case a == "--format" && i+1 < len(args):
+			i++
+			format, hasFormat = resolveFormat(cfg, args[i]), true
+		case strings.HasPrefix(a, "--format="):
+			format, hasFormat = resolveFormat(cfg, strings.TrimPrefix(a, "--format=")), true
+		case a == "--output" && i+1 < len(args):
+			i++
+			output = args[i]
+		case strings.HasPrefix(a, "--output="):
+			output = strings.TrimPrefix(a, "--output=")
+		}
+	}
+
+	if !slices.Contains(statusOutputs, output) {
+		fmt.Fprintf(os.Stderr, "error: unknown output %q (want one of %s)\n", output, strings.Join(statusOutputs, ", "))
+		os.Exit(2)
+	}
+
+	var tpl *template.Template
+	if hasFormat {
+		var err error
+		if tpl, err = parseFormat(format); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	snap := cachedSnapshot(context.Background(), cfg)
+	line := newStatusLine(snap)
+
+	if tpl != nil {
+		var b strings.Builder
+		if err := writeTemplate(&b, tpl, snap); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		// a template is colored as a whole, by its worst window
+		line.spans = []statusSpan{{text: strings.TrimSuffix(b.String(), "\n"), level: line.level, colored: true}}
+	} else {
+		if output == "plain" {
+			requireClaudeLogin(snap)
+		}
+		line.spans = compactSpans(snap, byModel)
+	}
+
+	if err := writeStatusLine(os.Stdout, line, output); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
+}
 
-	parts := []string{}
+// requireClaudeLogin exits with an error if the default Claude account is
+// enabled but has no credentials, as the plain compact line always has.
+// Only a failed fetch is checked, so the line stays instant otherwise.
+func requireClaudeLogin(snap Snapshot) {
+	i := slices.IndexFunc(snap.Providers, func(p ProviderSnapshot) bool { return p.Name == "claude" })
+	if i < 0 || snap.Providers[i].Error == "" || len(snap.Providers[i].Limits) > 0 {
+		return
+	}
+	if lp, ok := lookupProvider("claude").(loginProvider); ok {
+		if err := lp.Login(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+	}
+}
 
-	// Claude
-	if cfg.Providers.Claude {
-		usage, err := fetchUsage(token)
-		if err == nil && usage != nil {
-			var claudeParts []string
-			if usage.FiveHour != nil {
-				claudeParts = append(claudeParts, fmt.Sprintf("5h:%.0f%%", 100-usage.FiveHour.Utilization))
-			}
-			if usage.SevenDay != nil {
-				claudeParts = append(claudeParts, fmt.Sprintf("7d:%.0f%%", 100-usage.SevenDay.Utilization))
-			}
-			if len(claudeParts) > 0 {
-				parts = append(parts, "claude:"+joinWith(claudeParts, ","))
-			}
+// compactWindows are the rate-limit windows the built-in compact line
+// shows; scripts parse it, so others such as Opus are left to --format.
+var compactWindows = []string{"5h", "7d"}
+
+// compactSpans builds the built-in compact line: the remaining percentage
+// of the 5h and 7d windows, with the time each runs out if that is before
+// it resets, then the 7-day token total.
+func compactSpans(snap Snapshot, byModel bool) []statusSpan {
+	var spans []statusSpan
+	text := func(s string) {
+		if len(spans) > 0 && s != "," {
+			spans = append(spans, statusSpan{text: " "})
 		}
+		spans = append(spans, statusSpan{text: s})
 	}
 
-	// Codex
-	if cfg.Providers.Codex {
-		codexUsage, codexErr := fetchCodexUsage()
-		if codexErr == nil && codexUsage != nil {
-			var codexParts []string
-			if codexUsage.Primary != nil {
-				codexParts = append(codexParts, fmt.Sprintf("5h:%.0f%%", 100-codexUsage.Primary.UsedPercent))
+	for _, p := range snap.Providers {
+		var windows []LimitSnapshot
+		for _, w := range p.Limits {
+			if slices.Contains(compactWindows, w.Key) {
+				windows = append(windows, w)
 			}
-			if codexUsage.Secondary != nil {
-				codexParts = append(codexParts, fmt.Sprintf("7d:%.0f%%", 100-codexUsage.Secondary.UsedPercent))
+		}
+		if len(windows) == 0 {
+			continue
+		}
+		text(p.Name + ":")
+		for i, w := range windows {
+			if i > 0 {
+				spans = append(spans, statusSpan{text: ","})
 			}
-			if len(codexParts) > 0 {
-				parts = append(parts, "codex:"+joinWith(codexParts, ","))
+			spans = append(spans, statusSpan{
+				text:    fmt.Sprintf("%s:%.0f%%", w.Key, w.RemainingPercent),
+				level:   levelFor(w.UsedPercent),
+				colored: true,
+			})
+			if f := w.Forecast; f != nil && f.ExhaustsAt != nil {
+				spans = append(spans, statusSpan{
+					text:    "→" + f.ExhaustsAt.Local().Format("15:04"),
+					level:   max(levelWarning, levelFor(w.UsedPercent)),
+					colored: true,
+				})
 			}
 		}
-	}
-
-	// Token total (always shown if any provider is enabled)
-	if cfg.AnyEnabled() {
-		now := time.Now()
-		week, err := scanAllTokens(now.AddDate(0, 0, -7))
-		if err == nil && week.Total() > 0 {
-			parts = append(parts, "tok:"+formatTokenCount(week.Total()))
+		if p.Stale {
+			spans = append(spans, statusSpan{text: staleMark})
 		}
 	}
 
-	for i, p := range parts {
-		if i > 0 {
-			fmt.Print(" ")
+	// Token total (always shown if any provider is enabled)
+	if week := snap.Tokens.Week; len(snap.Providers) > 0 && snap.ScanError == "" && week.Total > 0 {
+		text("tok:" + formatTokenCount(week.Total))
+		if byModel {
+			for _, name := range week.SortedModels() {
+				text(shortModelName(name) + ":" + formatTokenCount(week.Models[name].Total))
+			}
 		}
-		fmt.Print(p)
 	}
-	fmt.Println()
+	return spans
 }
 
 func joinWith(parts []string, sep string) string {
@@ -18,8 +18,11 @@ type UsageBucket struct {
 type jsonlEntry struct {
 	Type      string `json:"type"`
 	Timestamp string `json:"timestamp"`
+	Cwd       string `json:"cwd"`
+	RequestID string `json:"requestId"`
 	Message   *struct {
 		ID    string `json:"id"`
+		Model string `json:"model"`
 		Usage *struct {
 			InputTokens              int `json:"input_tokens"`
 			OutputTokens             int `json:"output_tokens"`
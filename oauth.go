@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Claude Code's OAuth client, whose refresh tokens llm-usage redeems.
+const (
+	defaultClaudeTokenURL = "https://console.anthropic.com/v1/oauth/token"
+	claudeOAuthClientID   = "9d1c250a-e61b-44d9-88ed-5944d1962f5e"
+)
+
+// tokenRefreshMargin is how long before it expires an access token is
+// refreshed, so a request never goes out with a token that lapses on the
+// way.
+const tokenRefreshMargin = time.Minute
+
+// errTokenExpired is returned by fetchUsage when the API rejects the token.
+var errTokenExpired = errors.New("token expired — re-login to Claude Code")
+
+// expiresSoon reports whether the access token expires within
+// tokenRefreshMargin. Tokens without a known expiry never do.
+func (e OAuthEntry) expiresSoon(now time.Time) bool {
+	return e.ExpiresAt != 0 && now.Add(tokenRefreshMargin).UnixMilli() >= e.ExpiresAt
+}
+
+// refreshOAuthToken redeems a refresh token at tokenURL for a new access
+// token. The server may rotate the refresh token; if it doesn't, the old
+// one is kept.
+func refreshOAuthToken(ctx context.Context, tokenURL string, old OAuthEntry) (OAuthEntry, error) {
+	if tokenURL == "" {
+		tokenURL = defaultClaudeTokenURL
+	}
+	if u, err := url.Parse(tokenURL); err != nil || (u.Scheme != "https" && u.Hostname() != "127.0.0.1" && u.Hostname() != "localhost") {
+		return OAuthEntry{}, fmt.Errorf("invalid token URL %q: must be https", tokenURL)
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"grant_type":    "refresh_token",
+		"refresh_token": old.RefreshToken,
+		"client_id":     claudeOAuthClientID,
+	})
+	if err != nil {
+		return OAuthEntry{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, bytes.NewReader(body))
+	if err != nil {
+		return OAuthEntry{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return OAuthEntry{}, fmt.Errorf("token refresh failed: network error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	const maxTokenResponseBytes = 64 << 10
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTokenResponseBytes))
+	if err != nil {
+		return OAuthEntry{}, fmt.Errorf("token refresh failed: %w", err)
+	}
+	if resp.StatusCode == 400 || resp.StatusCode == 401 {
+		return OAuthEntry{}, fmt.Errorf("refresh token rejected — re-login to Claude Code")
+	}
+	if resp.StatusCode != 200 {
+		return OAuthEntry{}, fmt.Errorf("token refresh failed (HTTP %d)", resp.StatusCode)
+	}
+
+	var tok struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"` // seconds
+	}
+	if err := json.Unmarshal(data, &tok); err != nil {
+		return OAuthEntry{}, fmt.Errorf("token refresh failed: invalid response: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return OAuthEntry{}, fmt.Errorf("token refresh failed: no access token in response")
+	}
+
+	fresh := old
+	fresh.AccessToken = tok.AccessToken
+	if tok.RefreshToken != "" {
+		fresh.RefreshToken = tok.RefreshToken
+	}
+	fresh.ExpiresAt = 0
+	if tok.ExpiresIn > 0 {
+		fresh.ExpiresAt = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second).UnixMilli()
+	}
+	return fresh, nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tokenServer stands in for the OAuth token endpoint, answering every
+// request with status and body. It counts the requests it served.
+func tokenServer(t *testing.T, status int, body string) (*httptest.Server, *int) {
+	t.Helper()
+	calls := new(int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		var req map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("token request: %v", err)
+		}
+		if req["grant_type"] != "refresh_token" || req["client_id"] != claudeOAuthClientID || req["refresh_token"] == "" {
+			t.Errorf("token request = %v", req)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, calls
+}
+
+func TestRefreshOAuthTokenRotated(t *testing.T) {
+	srv, _ := tokenServer(t, 200, `{"access_token":"access-2","refresh_token":"refresh-2","expires_in":3600}`)
+	old := OAuthEntry{AccessToken: "access-1", RefreshToken: "refresh-1", SubscriptionType: "max"}
+
+	fresh, err := refreshOAuthToken(context.Background(), srv.URL, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.AccessToken != "access-2" || fresh.RefreshToken != "refresh-2" || fresh.SubscriptionType != "max" {
+		t.Errorf("fresh = %+v", fresh)
+	}
+	if d := time.UnixMilli(fresh.ExpiresAt).Sub(time.Now()); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("expires in %v, want 1h", d)
+	}
+}
+
+func TestRefreshOAuthTokenKept(t *testing.T) {
+	srv, _ := tokenServer(t, 200, `{"access_token":"access-2","expires_in":3600}`)
+	old := OAuthEntry{AccessToken: "access-1", RefreshToken: "refresh-1"}
+
+	fresh, err := refreshOAuthToken(context.Background(), srv.URL, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.AccessToken != "access-2" || fresh.RefreshToken != "refresh-1" {
+		t.Errorf("fresh = %+v", fresh)
+	}
+}
+
+func TestRefreshOAuthTokenRejected(t *testing.T) {
+	for _, status := range []int{400, 401} {
+		srv, _ := tokenServer(t, status, `{"error":"invalid_grant"}`)
+		_, err := refreshOAuthToken(context.Background(), srv.URL, OAuthEntry{AccessToken: "a", RefreshToken: "r"})
+		if err == nil || !strings.Contains(err.Error(), "re-login") {
+			t.Errorf("HTTP %d: err = %v, want a re-login error", status, err)
+		}
+	}
+}
+
+func TestRefreshOAuthTokenNoAccessToken(t *testing.T) {
+	srv, _ := tokenServer(t, 200, `{"refresh_token":"refresh-2"}`)
+	_, err := refreshOAuthToken(context.Background(), srv.URL, OAuthEntry{AccessToken: "a", RefreshToken: "r"})
+	if err == nil || !strings.Contains(err.Error(), "no access token") {
+		t.Errorf("err = %v, want no access token", err)
+	}
+}
+
+// usageServer stands in for the usage API, accepting only token.
+func usageServer(t *testing.T, token string) *int {
+	t.Helper()
+	calls := new(int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(401)
+			return
+		}
+		w.Write([]byte(`{"five_hour":{"utilization":42}}`))
+	}))
+	t.Cleanup(srv.Close)
+	old := claudeUsageURL
+	claudeUsageURL = srv.URL
+	t.Cleanup(func() { claudeUsageURL = old })
+	return calls
+}
+
+func TestFetchLimitsRefreshesOnce(t *testing.T) {
+	usageCalls := usageServer(t, "access-2")
+	tokenSrv, tokenCalls := tokenServer(t, 200, `{"access_token":"access-2","refresh_token":"refresh-2","expires_in":3600}`)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".credentials.json")
+	expires := time.Now().Add(time.Hour).UnixMilli() // revoked early, not expired
+	stored := fmt.Sprintf(`{"claudeAiOauth":{"accessToken":"access-1","refreshToken":"refresh-1","expiresAt":%d,"scopes":["user:inference"]},"mcpOAuth":{"x":1}}`, expires)
+	if err := os.WriteFile(path, []byte(stored), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &claudeProvider{profile: ClaudeProfile{Name: "test", ConfigDir: dir}, tokenURL: tokenSrv.URL}
+	limits, err := p.FetchLimits(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limits.Windows) != 1 || limits.Windows[0].UsedPercent != 42 {
+		t.Errorf("windows = %+v", limits.Windows)
+	}
+	if *usageCalls != 2 || *tokenCalls != 1 {
+		t.Errorf("usage calls = %d, token calls = %d; want 2 and 1", *usageCalls, *tokenCalls)
+	}
+
+	// the rotated tokens are written back, the rest of the file kept
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file struct {
+		ClaudeAiOauth struct {
+			AccessToken  string   `json:"accessToken"`
+			RefreshToken string   `json:"refreshToken"`
+			Scopes       []string `json:"scopes"`
+		} `json:"claudeAiOauth"`
+		McpOAuth map[string]int `json:"mcpOAuth"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	if o := file.ClaudeAiOauth; o.AccessToken != "access-2" || o.RefreshToken != "refresh-2" || len(o.Scopes) != 1 {
+		t.Errorf("stored credentials = %+v", o)
+	}
+	if file.McpOAuth["x"] != 1 {
+		t.Errorf("other fields lost: %s", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("credentials file mode = %v, %v", info.Mode(), err)
+	}
+}
+
+func TestFetchLimitsGivesUpAfterOneRefresh(t *testing.T) {
+	usageCalls := usageServer(t, "never")
+	tokenSrv, tokenCalls := tokenServer(t, 200, `{"access_token":"access-2","expires_in":3600}`)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".credentials.json"), []byte(`{"claudeAiOauth":{"accessToken":"access-1","refreshToken":"refresh-1"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &claudeProvider{profile: ClaudeProfile{Name: "test", ConfigDir: dir}, tokenURL: tokenSrv.URL}
+	if _, err := p.FetchLimits(context.Background()); !errors.Is(err, errTokenExpired) {
+		t.Errorf("err = %v, want %v", err, errTokenExpired)
+	}
+	if *usageCalls != 2 || *tokenCalls != 1 {
+		t.Errorf("usage calls = %d, token calls = %d; want 2 and 1", *usageCalls, *tokenCalls)
+	}
+}
+
+func TestFetchLimitsDoesNotRedeemCommandToken(t *testing.T) {
+	usageCalls := usageServer(t, "access-2")
+	tokenSrv, tokenCalls := tokenServer(t, 200, `{"access_token":"access-2","expires_in":3600}`)
+
+	p := &claudeProvider{
+		profile: ClaudeProfile{
+			Name:               "test",
+			ConfigDir:          t.TempDir(),
+			CredentialsCommand: []string{"echo", `{"claudeAiOauth":{"accessToken":"access-1","refreshToken":"refresh-1"}}`},
+		},
+		tokenURL: tokenSrv.URL,
+	}
+	if _, err := p.FetchLimits(context.Background()); !errors.Is(err, errTokenExpired) {
+		t.Errorf("err = %v, want %v", err, errTokenExpired)
+	}
+	if *usageCalls != 1 || *tokenCalls != 0 {
+		t.Errorf("usage calls = %d, token calls = %d; want 1 and 0", *usageCalls, *tokenCalls)
+	}
+}
+
+func TestFetchLimitsRedeemsOnceWhenExpiring(t *testing.T) {
+	usageCalls := usageServer(t, "never")
+	tokenSrv, tokenCalls := tokenServer(t, 200, `{"access_token":"access-2","refresh_token":"refresh-2","expires_in":3600}`)
+
+	dir := t.TempDir()
+	expired := time.Now().Add(-time.Minute).UnixMilli()
+	stored := fmt.Sprintf(`{"claudeAiOauth":{"accessToken":"access-1","refreshToken":"refresh-1","expiresAt":%d}}`, expired)
+	if err := os.WriteFile(filepath.Join(dir, ".credentials.json"), []byte(stored), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &claudeProvider{profile: ClaudeProfile{Name: "test", ConfigDir: dir}, tokenURL: tokenSrv.URL}
+	if _, err := p.FetchLimits(context.Background()); !errors.Is(err, errTokenExpired) {
+		t.Errorf("err = %v, want %v", err, errTokenExpired)
+	}
+	if *usageCalls != 1 || *tokenCalls != 1 {
+		t.Errorf("usage calls = %d, token calls = %d; want 1 and 1", *usageCalls, *tokenCalls)
+	}
+}
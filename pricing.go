@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ModelPrice is a model's API price in USD per million tokens.
+type ModelPrice struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheWrite float64 `json:"cache_write"`
+	CacheRead  float64 `json:"cache_read"`
+}
+
+// Cost returns the API-equivalent cost of s in USD. Reasoning tokens are
+// billed as output.
+func (p ModelPrice) Cost(s TokenStats) float64 {
+	return (float64(s.InputTokens)*p.Input +
+		float64(s.OutputTokens+s.Reasoning)*p.Output +
+		float64(s.CacheCreation)*p.CacheWrite +
+		float64(s.CacheRead)*p.CacheRead) / 1_000_000
+}
+
+// Pricing maps model-name prefixes to prices. A model is priced by the
+// longest prefix that matches its name, so "claude-opus-4" covers every
+// dated Opus 4 release unless a more specific entry exists.
+type Pricing map[string]ModelPrice
+
+// defaultPricing holds list prices at the time of writing. Users can
+// override or extend it through the "pricing" key in config.json.
+var defaultPricing = Pricing{
+	"claude-opus-4":     {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.50},
+	"claude-opus-4-5":   {Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.50},
+	"claude-sonnet-4":   {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+	"claude-3-7-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+	"claude-haiku-4-5":  {Input: 1, Output: 5, CacheWrite: 1.25, CacheRead: 0.10},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4, CacheWrite: 1, CacheRead: 0.08},
+	"gpt-5":             {Input: 1.25, Output: 10, CacheRead: 0.125},
+	"gpt-5-mini":        {Input: 0.25, Output: 2, CacheRead: 0.025},
+}
+
+// Lookup returns the price for a model using longest-prefix matching.
+func (p Pricing) Lookup(model string) (ModelPrice, bool) {
+	best := -1
+	var price ModelPrice
+	for prefix, mp := range p {
+		if strings.HasPrefix(model, prefix) && len(prefix) > best {
+			best = len(prefix)
+			price = mp
+		}
+	}
+	return price, best >= 0 && model != ""
+}
+
+// Cost returns the API-equivalent cost of stats in USD. Models without a
+// known price contribute nothing.
+func (p Pricing) Cost(stats ModelTokenStats) float64 {
+	var usd float64
+	for model, s := range stats {
+		if mp, ok := p.Lookup(model); ok {
+			usd += mp.Cost(s)
+		}
+	}
+	return usd
+}
+
+// Priced reports whether any model in stats has a known price.
+func (p Pricing) Priced(stats ModelTokenStats) bool {
+	for model := range stats {
+		if _, ok := p.Lookup(model); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// formatCost renders a USD amount compactly, e.g. "$0.42", "$128", "$1.2K".
+func formatCost(usd float64) string {
+	switch {
+	case usd >= 10_000:
+		return fmt.Sprintf("$%.1fK", usd/1_000)
+	case usd >= 100:
+		return fmt.Sprintf("$%.0f", usd)
+	default:
+		return fmt.Sprintf("$%.2f", usd)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// projectRoots caches projectRoot lookups; session files repeat the same
+// handful of working directories thousands of times.
+var projectRoots sync.Map // cwd -> root
+
+// projectRoot maps a working directory to the repository it belongs to:
+// the nearest ancestor containing .git. Directories outside a repository,
+// or that no longer exist, are returned unchanged.
+func projectRoot(cwd string) string {
+	if root, ok := projectRoots.Load(cwd); ok {
+		return root.(string)
+	}
+	root := filepath.Clean(cwd)
+	for dir := root; ; {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			root = dir
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	projectRoots.Store(cwd, root)
+	return root
+}
+
+// claudeProjectFallback returns the project for Claude entries that lack a
+// cwd: the encoded directory directly under the projects root, e.g.
+// "-Users-me-code-app". The encoding is lossy, so it is only a label.
+func claudeProjectFallback(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return ""
+	}
+	first, _, found := strings.Cut(filepath.ToSlash(rel), "/")
+	if !found {
+		return ""
+	}
+	return first
+}
+
+// shortProjectName renders a project path for display: its final element,
+// or "unknown" when no project was recorded.
+func shortProjectName(project string) string {
+	if project == "" {
+		return "unknown"
+	}
+	if strings.HasPrefix(project, "-") {
+		// encoded Claude directory name; keep the last segment
+		if i := strings.LastIndexByte(project, '-'); i > 0 {
+			return project[i+1:]
+		}
+	}
+	return filepath.Base(project)
+}
+
+// displayPath abbreviates the home directory in a path to "~".
+func displayPath(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return path
+	}
+	if path == home {
+		return "~"
+	}
+	if rest, ok := strings.CutPrefix(path, home+string(filepath.Separator)); ok {
+		return filepath.Join("~", rest)
+	}
+	return path
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Provider is a source of usage data: an LLM CLI tool whose rate limits
+// and/or local token counts llm-usage can display. Each implementation
+// lives in its own file and registers itself from init().
+type Provider interface {
+	// Name is the stable identifier used in config.json ("claude", "codex", ...).
+	Name() string
+	// Title is the display name shown in the TUI.
+	Title() string
+	// FetchLimits returns the current rate-limit windows. Providers that
+	// don't expose rate limits return nil, nil.
+	FetchLimits(ctx context.Context) (*RateLimits, error)
+	// ScanUsage calls emit for every usage event recorded since the given
+	// time. Events carry their timestamp and session, so a single scan can
+	// feed any number of windows through an Aggregator. emit is never
+	// called concurrently. A cancelled ctx stops the scan early with
+	// ctx.Err().
+	ScanUsage(ctx context.Context, since time.Time, emit func(UsageEvent)) error
+}
+
+// loginProvider is implemented by providers that need credentials before
+// they can fetch rate limits. Login is called once at startup.
+type loginProvider interface {
+	Login() error
+}
+
+// configProvider is implemented by providers with settings in
+// config.json. Configure is called once, before anything else.
+type configProvider interface {
+	Configure(cfg Config)
+}
+
+// profileProvider is implemented by providers that can track several
+// accounts. Profiles returns a provider for each further account in cfg.
+type profileProvider interface {
+	Profiles(cfg Config) []Provider
+}
+
+// watchProvider is implemented by providers whose usage comes from local
+// session files, so the TUI can pick up usage as it is appended.
+type watchProvider interface {
+	// WatchDirs returns the directories holding the session files.
+	WatchDirs() []string
+	// SessionFile returns how to scan the file at path, or false if path
+	// isn't one of the provider's session files.
+	SessionFile(path string) (sessionFile, usageFunc, bool)
+}
+
+// RateLimits is a provider's rate-limit state at the time of a fetch.
+type RateLimits struct {
+	Plan    string    // subscription type, if known
+	AsOf    time.Time // when the provider reported these limits; zero if unknown
+	Windows []LimitWindow
+}
+
+// LimitWindow is one rate-limit bucket, e.g. the 5-hour session window.
+type LimitWindow struct {
+	Key         string        // short id used in compact output: "5h", "7d", "opus"
+	Label       string        // full TUI label: "Session (5h)"
+	Short       string        // label for narrow terminals: "5h"
+	UsedPercent float64       // 0.0–100.0
+	ResetsAt    time.Time     // zero if unknown
+	Length      time.Duration // how long the window runs; zero if unknown
+}
+
+// providers holds every registered provider in registration order, which
+// is also the order of the TUI sections and the number-key toggles.
+var providers []Provider
+
+// profiles holds the names of the providers registered for the profiles
+// in the config, rather than from init().
+var profiles = make(map[string]bool)
+
+// registerProvider adds a provider to the registry. It panics on duplicate
+// names since that can only be a programming error.
+func registerProvider(p Provider) {
+	if lookupProvider(p.Name()) != nil {
+		panic(fmt.Sprintf("provider %q registered twice", p.Name()))
+	}
+	providers = append(providers, p)
+}
+
+// lookupProvider returns the registered provider with the given name, or nil.
+func lookupProvider(name string) Provider {
+	for _, p := range providers {
+		if p.Name() == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// configureProviders passes cfg to every registered provider that takes
+// settings, then registers the profiles in cfg right after the provider
+// they belong to. Profiles whose name is taken are skipped.
+func configureProviders(cfg Config) {
+	for _, p := range providers {
+		if cp, ok := p.(configProvider); ok {
+			cp.Configure(cfg)
+		}
+	}
+	var all []Provider
+	for _, p := range providers {
+		all = append(all, p)
+		pp, ok := p.(profileProvider)
+		if !ok {
+			continue
+		}
+		for _, profile := range pp.Profiles(cfg) {
+			taken := slices.ContainsFunc(all, func(q Provider) bool { return q.Name() == profile.Name() })
+			if !taken && lookupProvider(profile.Name()) == nil {
+				all = append(all, profile)
+				profiles[profile.Name()] = true
+			}
+		}
+	}
+	providers = all
+}
+
+// enabledProviders returns the registered providers enabled in cfg.
+func enabledProviders(cfg Config) []Provider {
+	var out []Provider
+	for _, p := range providers {
+		if cfg.Enabled(p.Name()) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
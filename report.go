@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// reportRow is the usage of one group in a report.
+type reportRow struct {
+	Group  string
+	Tokens TokenStats
+	Cost   float64
+	Priced bool // whether any of the row's models has a known price
+}
+
+// runReport implements the report subcommand: token usage over an
+// arbitrary date range, grouped by period, provider, model or project.
+// It returns the process exit code.
+func runReport(cfg Config, args []string) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: llm-usage report [--since DATE] [--until DATE] [--group-by GROUP] [--format FORMAT]")
+		fmt.Fprintln(fs.Output(), "\nDATE is YYYY-MM-DD or relative to now, e.g. 30d, 2w, 3m.")
+		fs.PrintDefaults()
+	}
+	sinceFlag := fs.String("since", "", "start of the range, inclusive (default: start of this month)")
+	untilFlag := fs.String("until", "", "end of the range; a date includes that whole day (default: now)")
+	groupBy := fs.String("group-by", "day", "day, week, month, provider, model or project")
+	format := fs.String("format", "table", "table, json or csv")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	now := time.Now()
+	since := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	until := now
+	var err error
+	if *sinceFlag != "" {
+		if since, err = parseReportDate(*sinceFlag, now, false); err != nil {
+			fmt.Fprintf(os.Stderr, "error: --since: %v\n", err)
+			return 2
+		}
+	}
+	if *untilFlag != "" {
+		if until, err = parseReportDate(*untilFlag, now, true); err != nil {
+			fmt.Fprintf(os.Stderr, "error: --until: %v\n", err)
+			return 2
+		}
+	}
+	if !since.Before(until) {
+		fmt.Fprintln(os.Stderr, "error: --since must be before --until")
+		return 2
+	}
+
+	switch *groupBy {
+	case "day", "week", "month", "provider", "model", "project":
+	default:
+		fmt.Fprintf(os.Stderr, "error: unknown grouping %q\n", *groupBy)
+		return 2
+	}
+
+	var write func(io.Writer, time.Time, time.Time, []reportRow, reportRow) error
+	switch *format {
+	case "table":
+		write = writeReportTable
+	case "json":
+		write = writeReportJSON
+	case "csv":
+		write = writeReportCSV
+	default:
+		fmt.Fprintf(os.Stderr, "error: unknown format %q\n", *format)
+		return 2
+	}
+
+	rows, err := buildReport(context.Background(), cfg, since, until, *groupBy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	total := reportRow{Group: "total"}
+	for _, r := range rows {
+		total.Tokens = total.Tokens.Add(r.Tokens)
+		total.Cost += r.Cost
+		total.Priced = total.Priced || r.Priced
+	}
+	if err := write(os.Stdout, since, until, rows, total); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// parseReportDate parses an absolute date (YYYY-MM-DD, local time) or a
+// duration back from now ("30d", "2w", "3m"). An absolute end date means
+// the end of that day.
+func parseReportDate(s string, now time.Time, end bool) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	if len(s) < 2 {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	switch s[len(s)-1] {
+	case 'd':
+		return now.AddDate(0, 0, -n), nil
+	case 'w':
+		return now.AddDate(0, 0, -7*n), nil
+	case 'm':
+		return now.AddDate(0, -n, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// buildReport scans the enabled providers over [since, until) and groups
+// the usage. Periods come out in chronological order, everything else by
+// descending total; groups without usage are left out.
+func buildReport(ctx context.Context, cfg Config, since, until time.Time, groupBy string) ([]reportRow, error) {
+	enabled := enabledProviders(cfg)
+	pricing := cfg.PricingTable()
+	row := func(group string, models ModelTokenStats) reportRow {
+		return reportRow{
+			Group:  group,
+			Tokens: models.Sum(),
+			Cost:   pricing.Cost(models),
+			Priced: pricing.Priced(models),
+		}
+	}
+
+	usage := newAggregator()
+	var periods []string
+	switch groupBy {
+	case "day", "week", "month":
+		for start := reportPeriodStart(since, groupBy); start.Before(until); {
+			next := reportPeriodNext(start, groupBy)
+			label := start.Format("2006-01-02")
+			if groupBy == "month" {
+				label = start.Format("2006-01")
+			}
+			usage.Window(label, Window{Since: maxTime(start, since), Until: minTime(next, until)})
+			periods = append(periods, label)
+			start = next
+		}
+	case "provider", "model", "project":
+		usage.Window("all", Window{Since: since, Until: until})
+	default:
+		return nil, fmt.Errorf("unknown grouping %q", groupBy)
+	}
+	if err := scanUsage(ctx, enabled, usage); err != nil {
+		return nil, err
+	}
+
+	var rows []reportRow
+	add := func(r reportRow) {
+		if r.Tokens.Total() > 0 {
+			rows = append(rows, r)
+		}
+	}
+	switch groupBy {
+	case "provider":
+		for _, p := range enabled {
+			add(row(p.Name(), usage.Tokens("all", []Provider{p}).ByModel()))
+		}
+		sortReportRows(rows)
+	case "model":
+		models := usage.Tokens("all", enabled).ByModel()
+		for _, name := range models.Sorted() {
+			add(row(orUnknown(name), ModelTokenStats{name: models[name]}))
+		}
+	case "project":
+		projects := usage.Tokens("all", enabled).ByProject()
+		for _, name := range projects.Sorted() {
+			add(row(orUnknown(name), projects[name]))
+		}
+	default:
+		for _, label := range periods {
+			add(row(label, usage.Tokens(label, enabled).ByModel()))
+		}
+	}
+	return rows, nil
+}
+
+// reportPeriodStart returns the start of the day, week (Monday) or month
+// holding t.
+func reportPeriodStart(t time.Time, period string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch period {
+	case "week":
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case "month":
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day
+}
+
+// reportPeriodNext returns the start of the period after the one starting
+// at start.
+func reportPeriodNext(start time.Time, period string) time.Time {
+	switch period {
+	case "week":
+		return start.AddDate(0, 0, 7)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// sortReportRows orders rows by descending total tokens.
+func sortReportRows(rows []reportRow) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].Tokens.Total() > rows[j].Tokens.Total()
+	})
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// writeReportTable prints the report as an aligned table with a total row.
+// Input counts include cache reads and writes, as in the TUI.
+func writeReportTable(w io.Writer, since, until time.Time, rows []reportRow, total reportRow) error {
+	width := len("total")
+	for _, r := range rows {
+		width = max(width, len(r.Group))
+	}
+	fmt.Fprintf(w, "%s → %s\n", since.Format("2006-01-02 15:04"), until.Format("2006-01-02 15:04"))
+	fmt.Fprintf(w, "%-*s %7s %7s %7s %15s %7s %8s\n", width, "", "in", "out", "rsn", "cache r/w", "total", "≈$")
+	line := func(r reportRow) {
+		t := r.Tokens
+		cost := "-"
+		if r.Priced {
+			cost = formatCost(r.Cost)
+		}
+		fmt.Fprintf(w, "%-*s %7s %7s %7s %15s %7s %8s\n", width, r.Group,
+			formatTokenCount(t.InputTokens+t.CacheCreation+t.CacheRead),
+			formatTokenCount(t.OutputTokens), formatTokenCount(t.Reasoning),
+			formatTokenCount(t.CacheRead)+"/"+formatTokenCount(t.CacheCreation),
+			formatTokenCount(t.Total()), cost)
+	}
+	for _, r := range rows {
+		line(r)
+	}
+	if len(rows) > 1 {
+		line(total)
+	}
+	return nil
+}
+
+// reportJSONRow is a report row in JSON and CSV output. Token classes
+// don't overlap: input_tokens excludes cache reads and writes. The cost is
+// null when no model in the row has a known price.
+type reportJSONRow struct {
+	Group               string   `json:"group"`
+	InputTokens         int      `json:"input_tokens"`
+	OutputTokens        int      `json:"output_tokens"`
+	ReasoningTokens     int      `json:"reasoning_tokens"`
+	CacheCreationTokens int      `json:"cache_creation_tokens"`
+	CacheReadTokens     int      `json:"cache_read_tokens"`
+	TotalTokens         int      `json:"total_tokens"`
+	CostUSD             *float64 `json:"cost_usd"`
+}
+
+func newReportJSONRow(r reportRow) reportJSONRow {
+	j := reportJSONRow{
+		Group:               r.Group,
+		InputTokens:         r.Tokens.InputTokens,
+		OutputTokens:        r.Tokens.OutputTokens,
+		ReasoningTokens:     r.Tokens.Reasoning,
+		CacheCreationTokens: r.Tokens.CacheCreation,
+		CacheReadTokens:     r.Tokens.CacheRead,
+		TotalTokens:         r.Tokens.Total(),
+	}
+	if r.Priced {
+		cost := r.Cost
+		j.CostUSD = &cost
+	}
+	return j
+}
+
+func writeReportJSON(w io.Writer, since, until time.Time, rows []reportRow, total reportRow) error {
+	out := struct {
+		Since time.Time       `json:"since"`
+		Until time.Time       `json:"until"`
+		Rows  []reportJSONRow `json:"rows"`
+		Total reportJSONRow   `json:"total"`
+	}{Since: since, Until: until, Rows: []reportJSONRow{}, Total: newReportJSONRow(total)}
+	for _, r := range rows {
+		out.Rows = append(out.Rows, newReportJSONRow(r))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func writeReportCSV(w io.Writer, since, until time.Time, rows []reportRow, total reportRow) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"group", "input_tokens", "output_tokens", "reasoning_tokens",
+		"cache_creation_tokens", "cache_read_tokens", "total_tokens", "cost_usd"})
+	for _, r := range rows {
+		j := newReportJSONRow(r)
+		cost := "" // unknown
+		if j.CostUSD != nil {
+			cost = strconv.FormatFloat(*j.CostUSD, 'f', 4, 64)
+		}
+		cw.Write([]string{j.Group,
+			strconv.Itoa(j.InputTokens), strconv.Itoa(j.OutputTokens), strconv.Itoa(j.ReasoningTokens),
+			strconv.Itoa(j.CacheCreationTokens), strconv.Itoa(j.CacheReadTokens), strconv.Itoa(j.TotalTokens),
+			cost})
+	}
+	cw.Flush()
+	return cw.Error()
+}
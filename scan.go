@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionFile is a session file to scan, with the parser for its lines.
+type sessionFile struct {
+	path  string
+	id    string // session ID shown in the session browser
+	parse lineParser
+}
+
+// usageFunc reports the usage recorded in a file's parsed state since the
+// given time, calling emit once per contribution. Each provider implements
+// its own accounting on top of the raw records. Contributions that may be
+// copied into other files carry an ID, so they are only counted once.
+type usageFunc func(st *fileState, since time.Time, emit func(t time.Time, id string, key UsageKey, s TokenStats))
+
+// scanWorkers bounds how many files a single scan parses at once.
+var scanWorkers = runtime.GOMAXPROCS(0)
+
+// scanFiles emits the usage of files since the given time as events of
+// the named provider. Files are parsed by a bounded pool of workers; their
+// events are emitted from the calling goroutine, in file order, once every
+// file is done.
+func scanFiles(ctx context.Context, provider string, files []sessionFile, usage usageFunc, since time.Time, emit func(UsageEvent)) error {
+	results := make([][]UsageEvent, len(files))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(scanWorkers, len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = scanFile(ctx, provider, files[i], usage, since)
+			}
+		}()
+	}
+feed:
+	for i := range files {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+	index.flush()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, events := range results {
+		for _, e := range events {
+			emit(e)
+		}
+	}
+	return nil
+}
+
+// scanFile collects the usage events of one file.
+func scanFile(ctx context.Context, provider string, f sessionFile, usage usageFunc, since time.Time) []UsageEvent {
+	var events []UsageEvent
+	index.withFile(ctx, f.path, f.parse, func(st *fileState) {
+		ref := SessionRef{
+			Provider: provider,
+			ID:       f.id,
+			Path:     f.path,
+			Start:    recordTime(usageRecord{Time: st.First}),
+		}
+		usage(st, since, func(t time.Time, id string, key UsageKey, s TokenStats) {
+			events = append(events, UsageEvent{Time: t, ID: id, Key: key, Tokens: s, Session: ref})
+		})
+	})
+	return events
+}
+
+// cumulativeUsage reports the records of st, each a running session total,
+// as the deltas between successive records. A total that shrinks means the
+// counter was reset, so the new total is all new usage. Records without a
+// timestamp are always counted, as the file itself is recent.
+func cumulativeUsage(st *fileState, since time.Time, emit func(time.Time, string, UsageKey, TokenStats)) {
+	var prev TokenStats
+	for _, r := range st.Records {
+		delta := r.Tokens.Sub(prev)
+		if delta.InputTokens < 0 || delta.OutputTokens < 0 || delta.CacheCreation < 0 || delta.CacheRead < 0 || delta.Reasoning < 0 {
+			delta = r.Tokens
+		}
+		prev = r.Tokens
+		if delta == (TokenStats{}) {
+			continue
+		}
+		if t := recordTime(r); t.IsZero() || !t.Before(since) {
+			emit(t, "", st.Keys[r.Key], delta)
+		}
+	}
+}
+
+// rescanFiles brings the given session files up to date and returns their
+// usage since the given time, by path. A file that no longer exists maps
+// to no events; paths no provider recognizes are left out. The index is
+// not flushed: the watcher calls this every second while an agent writes,
+// so the next full scan or quitting the TUI writes it instead.
+func rescanFiles(ctx context.Context, ps []Provider, paths []string, since time.Time) map[string][]UsageEvent {
+	out := make(map[string][]UsageEvent)
+	for _, path := range paths {
+		for _, p := range ps {
+			wp, ok := p.(watchProvider)
+			if !ok {
+				continue
+			}
+			if f, usage, ok := wp.SessionFile(path); ok {
+				out[path] = scanFile(ctx, p.Name(), f, usage, since)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// withinDir reports whether path lies inside dir.
+func withinDir(dir, path string) bool {
+	if dir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// existingDirs returns those of dirs that exist.
+func existingDirs(dirs ...string) []string {
+	var out []string
+	for _, d := range dirs {
+		if info, err := os.Stat(d); err == nil && info.IsDir() {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// inWindow reports whether t falls in [since, until); a zero until means
+// no upper bound.
+func inWindow(t, since, until time.Time) bool {
+	return !t.Before(since) && (until.IsZero() || t.Before(until))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Session is the token usage of one conversation, i.e. one session file.
+type Session struct {
+	Provider string
+	ID       string
+	Path     string
+	Start    time.Time // first counted entry
+	End      time.Time // last counted entry
+	Project  string    // project with the most tokens
+	Model    string    // model with the most tokens
+	Tokens   TokenBreakdown
+}
+
+// newSession builds a Session, picking the dominant model and project.
+func newSession(provider, id, path string, start, end time.Time, tokens TokenBreakdown) Session {
+	s := Session{
+		Provider: provider,
+		ID:       id,
+		Path:     path,
+		Start:    start,
+		End:      end,
+		Tokens:   tokens,
+	}
+	if models := tokens.ByModel().Sorted(); len(models) > 0 {
+		s.Model = models[0]
+	}
+	if projects := tokens.ByProject().Sorted(); len(projects) > 0 {
+		s.Project = projects[0]
+	}
+	return s
+}
+
+// sessionSort is an ordering for the session browser.
+type sessionSort int
+
+const (
+	sortByRecent sessionSort = iota
+	sortByTokens
+	sortByCost
+	numSessionSorts
+)
+
+func (o sessionSort) String() string {
+	switch o {
+	case sortByTokens:
+		return "tokens"
+	case sortByCost:
+		return "cost"
+	default:
+		return "recent"
+	}
+}
+
+// sortSessions orders sessions in place, largest or most recent first.
+func sortSessions(sessions []Session, order sessionSort, pricing Pricing) {
+	key := func(s Session) float64 {
+		switch order {
+		case sortByTokens:
+			return float64(s.Tokens.Sum().Total())
+		case sortByCost:
+			return pricing.Cost(s.Tokens.ByModel())
+		default:
+			return float64(s.End.UnixNano())
+		}
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return key(sessions[i]) > key(sessions[j])
+	})
+}
+
+// formatDuration renders a session length as "45m" or "2h 38m".
+func formatDuration(d time.Duration) string {
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+}
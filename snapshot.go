@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// snapshotVersion is the version of the --json schema. It is bumped when a
+// field is removed, renamed or changes meaning; adding fields doesn't.
+const snapshotVersion = 1
+
+// Snapshot is the state of every enabled provider at one point in time.
+// It is what --json prints and what compact output is rendered from.
+type Snapshot struct {
+	Version     int                `json:"version"`
+	GeneratedAt time.Time          `json:"generated_at"`
+	Providers   []ProviderSnapshot `json:"providers"`
+	Tokens      TokenWindows       `json:"tokens"`               // all providers combined
+	ScanError   string             `json:"scan_error,omitempty"` // reading session files failed
+}
+
+// ProviderSnapshot is one provider's rate limits and token usage.
+type ProviderSnapshot struct {
+	Name   string          `json:"name"`
+	Plan   string          `json:"plan,omitempty"`
+	Limits []LimitSnapshot `json:"limits"` // empty if the provider has no rate limits
+	// AsOf is when the provider reported its limits; for Codex that is the
+	// last session that recorded them, which may be long ago.
+	AsOf       *time.Time `json:"as_of,omitempty"`
+	AgeSeconds *float64   `json:"age_seconds,omitempty"`
+	Error      string     `json:"error,omitempty"` // fetching the limits failed
+	// Stale is set when Limits are the last good values from the cache
+	// because fetching fresh ones failed with Error.
+	Stale  bool         `json:"stale,omitempty"`
+	Tokens TokenWindows `json:"tokens"`
+}
+
+// LimitSnapshot is one rate-limit window.
+type LimitSnapshot struct {
+	Key              string     `json:"key"`
+	Label            string     `json:"label"`
+	UsedPercent      float64    `json:"used_percent"`
+	RemainingPercent float64    `json:"remaining_percent"`
+	ResetsAt         *time.Time `json:"resets_at,omitempty"`
+	// Forecast is missing until enough utilization history is recorded.
+	Forecast *ForecastSnapshot `json:"forecast,omitempty"`
+}
+
+// ForecastSnapshot projects a window at its current burn rate.
+type ForecastSnapshot struct {
+	PercentPerHour float64 `json:"percent_per_hour"`
+	// ExhaustsAt is when the window reaches 100%; null if it resets first.
+	ExhaustsAt *time.Time `json:"exhausts_at"`
+	OnTrack    bool       `json:"on_track"`
+}
+
+// TokenWindows is token usage over the windows the TUI shows.
+type TokenWindows struct {
+	Today TokenSnapshot `json:"today"`
+	Week  TokenSnapshot `json:"last_7_days"`
+	Month TokenSnapshot `json:"month"`
+}
+
+// TokenSnapshot is token usage by class, with a per-model breakdown.
+type TokenSnapshot struct {
+	TokenClasses
+	Models map[string]TokenClasses `json:"models,omitempty"`
+}
+
+// TokenClasses splits token usage into non-overlapping classes.
+type TokenClasses struct {
+	Input         int      `json:"input"`
+	Output        int      `json:"output"`
+	Reasoning     int      `json:"reasoning"`
+	CacheCreation int      `json:"cache_creation"`
+	CacheRead     int      `json:"cache_read"`
+	Total         int      `json:"total"`
+	CostUSD       *float64 `json:"cost_usd"` // null if no model has a known price
+}
+
+func newTokenClasses(models ModelTokenStats, pricing Pricing) TokenClasses {
+	s := models.Sum()
+	c := TokenClasses{
+		Input:         s.InputTokens,
+		Output:        s.OutputTokens,
+		Reasoning:     s.Reasoning,
+		CacheCreation: s.CacheCreation,
+		CacheRead:     s.CacheRead,
+		Total:         s.Total(),
+	}
+	if pricing.Priced(models) {
+		cost := pricing.Cost(models)
+		c.CostUSD = &cost
+	}
+	return c
+}
+
+func newTokenSnapshot(b TokenBreakdown, pricing Pricing) TokenSnapshot {
+	models := b.ByModel()
+	t := TokenSnapshot{TokenClasses: newTokenClasses(models, pricing)}
+	for name, s := range models {
+		if s.Total() == 0 {
+			continue
+		}
+		if t.Models == nil {
+			t.Models = make(map[string]TokenClasses)
+		}
+		t.Models[orUnknown(name)] = newTokenClasses(ModelTokenStats{name: s}, pricing)
+	}
+	return t
+}
+
+// SortedModels returns the model names ordered by descending total tokens.
+func (t TokenSnapshot) SortedModels() []string {
+	names := make([]string, 0, len(t.Models))
+	for name := range t.Models {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ti, tj := t.Models[names[i]].Total, t.Models[names[j]].Total
+		if ti != tj {
+			return ti > tj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// collectSnapshot fetches the rate limits of every enabled provider and
+// scans their token usage, all concurrently. The limits fetched are
+// appended to the history, like those of the TUI.
+func collectSnapshot(ctx context.Context, cfg Config) Snapshot {
+	now := time.Now()
+	enabled := enabledProviders(cfg)
+	pricing := cfg.PricingTable()
+
+	limits := make([]*RateLimits, len(enabled))
+	errs := make([]error, len(enabled))
+	var wg sync.WaitGroup
+	for i, p := range enabled {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limits[i], errs[i] = p.FetchLimits(ctx)
+			if limits[i] != nil {
+				// status bars poll often enough to keep the forecasts going
+				recordLimits(p.Name(), limits[i])
+			}
+		}()
+	}
+
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	usage := newAggregator()
+	usage.Window(windowToday, Window{Since: startOfDay})
+	usage.Window(windowWeek, Window{Since: now.AddDate(0, 0, -7)})
+	usage.Window(windowMonth, Window{Since: startOfDay.AddDate(0, 0, 1-now.Day())})
+	scanErr := scanUsage(ctx, enabled, usage)
+	if os.Getenv("LLM_USAGE_DEBUG") != "" {
+		fmt.Fprintf(os.Stderr, "debug: dropped %d duplicate messages\n", usage.Duplicates())
+	}
+	wg.Wait()
+	history, _ := readHistory(now.AddDate(0, 0, -8))
+
+	tokens := func(ps []Provider) TokenWindows {
+		return TokenWindows{
+			Today: newTokenSnapshot(usage.Tokens(windowToday, ps), pricing),
+			Week:  newTokenSnapshot(usage.Tokens(windowWeek, ps), pricing),
+			Month: newTokenSnapshot(usage.Tokens(windowMonth, ps), pricing),
+		}
+	}
+
+	snap := Snapshot{
+		Version:     snapshotVersion,
+		GeneratedAt: now,
+		Providers:   []ProviderSnapshot{},
+		Tokens:      tokens(enabled),
+	}
+	if scanErr != nil {
+		snap.ScanError = scanErr.Error()
+	}
+	for i, p := range enabled {
+		ps := ProviderSnapshot{
+			Name:   p.Name(),
+			Limits: []LimitSnapshot{},
+			Tokens: tokens([]Provider{p}),
+		}
+		if errs[i] != nil {
+			ps.Error = errs[i].Error()
+		}
+		if l := limits[i]; l != nil {
+			ps.Plan = l.Plan
+			asOf := now
+			if !l.AsOf.IsZero() {
+				asOf = l.AsOf
+				age := now.Sub(asOf).Seconds()
+				ps.AsOf, ps.AgeSeconds = &asOf, &age
+			}
+			for _, w := range l.Windows {
+				ls := LimitSnapshot{
+					Key:              w.Key,
+					Label:            w.Label,
+					UsedPercent:      w.UsedPercent,
+					RemainingPercent: 100 - w.UsedPercent,
+				}
+				if !w.ResetsAt.IsZero() {
+					resetsAt := w.ResetsAt
+					ls.ResetsAt = &resetsAt
+				}
+				if f, ok := forecastWindow(history, p.Name(), w, asOf); ok {
+					ls.Forecast = &ForecastSnapshot{PercentPerHour: f.PercentPerHour, OnTrack: f.OnTrack()}
+					if !f.OnTrack() {
+						ls.Forecast.ExhaustsAt = &f.ExhaustsAt
+					}
+				}
+				ps.Limits = append(ps.Limits, ls)
+			}
+		}
+		snap.Providers = append(snap.Providers, ps)
+	}
+	return snap
+}
+
+// writeJSON prints the snapshot as indented JSON.
+func (s Snapshot) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// defaultCacheTTL is how long a cached snapshot is served before the next
+// invocation fetches a new one.
+const defaultCacheTTL = time.Minute
+
+// snapshotCache is the on-disk form of the shared result cache.
+type snapshotCache struct {
+	Providers []string // enabled providers the snapshot was taken for
+	Snapshot  Snapshot
+}
+
+// snapshotCachePath returns the full path to the cached snapshot.
+func snapshotCachePath() string {
+	return filepath.Join(cacheDir(), "snapshot.json")
+}
+
+// cachedSnapshot returns a snapshot no older than the configured TTL,
+// shared by every llm-usage process: status bars run compact mode from
+// many places at once, and only one of them should fetch. A provider whose
+// fetch fails keeps its last good limits, marked stale. LLM_USAGE_DEBUG
+// bypasses the cache, so the debug output of a scan is always printed.
+func cachedSnapshot(ctx context.Context, cfg Config) Snapshot {
+	ttl := cfg.SnapshotTTL()
+	if ttl <= 0 || os.Getenv("LLM_USAGE_DEBUG") != "" {
+		return collectSnapshot(ctx, cfg)
+	}
+	key := providerNames(enabledProviders(cfg))
+
+	cached, ok := readSnapshotCache(key)
+	if ok && time.Since(cached.GeneratedAt) < ttl {
+		return cached
+	}
+
+	if err := os.MkdirAll(cacheDir(), 0755); err != nil {
+		return collectSnapshot(ctx, cfg)
+	}
+	lock, err := os.OpenFile(snapshotCachePath()+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return collectSnapshot(ctx, cfg)
+	}
+	defer lock.Close()
+	if err := lockFile(lock); err != nil {
+		return collectSnapshot(ctx, cfg)
+	}
+	defer unlockFile(lock)
+
+	// another process may have fetched while we waited for the lock
+	cached, ok = readSnapshotCache(key)
+	if ok && time.Since(cached.GeneratedAt) < ttl {
+		return cached
+	}
+
+	snap := collectSnapshot(ctx, cfg)
+	if ok {
+		keepLastGood(&snap, cached)
+	}
+	writeSnapshotCache(snapshotCache{Providers: key, Snapshot: snap})
+	return snap
+}
+
+// keepLastGood gives every provider whose limits couldn't be fetched the
+// limits from prev, marked stale. Windows that have reset since are
+// reported as unused.
+func keepLastGood(snap *Snapshot, prev Snapshot) {
+	for i := range snap.Providers {
+		p := &snap.Providers[i]
+		if p.Error == "" {
+			continue
+		}
+		for _, old := range prev.Providers {
+			if old.Name != p.Name || len(old.Limits) == 0 {
+				continue
+			}
+			p.Plan, p.AsOf, p.Stale = old.Plan, old.AsOf, true
+			if p.AsOf != nil {
+				age := snap.GeneratedAt.Sub(*p.AsOf).Seconds()
+				p.AgeSeconds = &age
+			}
+			p.Limits = slices.Clone(old.Limits)
+			for j := range p.Limits {
+				if w := &p.Limits[j]; w.ResetsAt != nil && w.ResetsAt.Before(snap.GeneratedAt) {
+					w.UsedPercent, w.RemainingPercent, w.ResetsAt = 0, 100, nil
+				}
+			}
+		}
+	}
+}
+
+// readSnapshotCache returns the cached snapshot if there is one for the
+// given providers in the current schema.
+func readSnapshotCache(providers []string) (Snapshot, bool) {
+	data, err := os.ReadFile(snapshotCachePath())
+	if err != nil {
+		return Snapshot{}, false
+	}
+	var c snapshotCache
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Snapshot{}, false
+	}
+	if c.Snapshot.Version != snapshotVersion || !slices.Equal(c.Providers, providers) {
+		return Snapshot{}, false
+	}
+	return c.Snapshot, true
+}
+
+// writeSnapshotCache writes the cache to a temp file and renames it into
+// place, so readers that don't take the lock never see a partial file.
+func writeSnapshotCache(c snapshotCache) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot cache: %w", err)
+	}
+	tmp, err := os.CreateTemp(cacheDir(), "snapshot-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), snapshotCachePath()); err != nil {
+		return fmt.Errorf("failed to write snapshot cache: %w", err)
+	}
+	return nil
+}
+
+// providerNames returns the names of ps.
+func providerNames(ps []Provider) []string {
+	names := make([]string, len(ps))
+	for i, p := range ps {
+		names[i] = p.Name()
+	}
+	return names
+}
+
+// staleMark is appended to the compact output of a provider whose limits
+// are the last good values rather than fresh ones.
+const staleMark = "*"
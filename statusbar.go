@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// usageLevel grades how much of a rate-limit window is used. The TUI
+// colors its percentages by level, and status bar outputs color their
+// text the same way.
+type usageLevel int
+
+const (
+	levelOK usageLevel = iota
+	levelWarning
+	levelCritical
+)
+
+// Usage thresholds, in percent used.
+const (
+	warningPercent  = 75
+	criticalPercent = 90
+)
+
+func levelFor(usedPercent float64) usageLevel {
+	switch {
+	case usedPercent >= criticalPercent:
+		return levelCritical
+	case usedPercent >= warningPercent:
+		return levelWarning
+	default:
+		return levelOK
+	}
+}
+
+// String returns the level's CSS class name, as used by Waybar.
+func (l usageLevel) String() string {
+	switch l {
+	case levelCritical:
+		return "critical"
+	case levelWarning:
+		return "warning"
+	default:
+		return "ok"
+	}
+}
+
+// Color returns the level's hex color: the two ends of the TUI bar
+// gradient for ok and critical, amber in between.
+func (l usageLevel) Color() string {
+	switch l {
+	case levelCritical:
+		return "#FF6347"
+	case levelWarning:
+		return "#FFC857"
+	default:
+		return "#76EEC6"
+	}
+}
+
+// statusSpan is a piece of a status line. Spans with colored set take the
+// color of their level in outputs that support colors.
+type statusSpan struct {
+	text    string
+	level   usageLevel
+	colored bool
+}
+
+// statusLine is a status line ready to be written in any output format.
+type statusLine struct {
+	spans   []statusSpan
+	tooltip string
+	level   usageLevel // worst level of any window
+	percent float64    // highest percent used of any window
+}
+
+// plain returns the line without colors.
+func (l statusLine) plain() string {
+	var b strings.Builder
+	for _, s := range l.spans {
+		b.WriteString(s.text)
+	}
+	return b.String()
+}
+
+// colored returns the line with each colored span wrapped by wrap, after
+// escaping the text of every span with escape.
+func (l statusLine) colored(wrap func(color, text string) string, escape func(string) string) string {
+	var b strings.Builder
+	for _, s := range l.spans {
+		text := escape(s.text)
+		if s.colored {
+			text = wrap(s.level.Color(), text)
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+// newStatusLine summarizes the snapshot's levels and builds the tooltip;
+// the caller adds the spans.
+func newStatusLine(snap Snapshot) statusLine {
+	var l statusLine
+	var tip []string
+	for _, p := range snap.Providers {
+		if p.Error != "" {
+			tip = append(tip, p.Name+": "+p.Error)
+		}
+		for _, w := range p.Limits {
+			l.level = max(l.level, levelFor(w.UsedPercent))
+			l.percent = max(l.percent, w.UsedPercent)
+			line := fmt.Sprintf("%s %s: %.0f%% used", p.Name, w.Label, w.UsedPercent)
+			if w.ResetsAt != nil {
+				line += ", " + formatReset(*w.ResetsAt)
+			}
+			if f := w.Forecast; f != nil && f.ExhaustsAt != nil {
+				line += ", " + formatExhaustion(*f.ExhaustsAt, snap.GeneratedAt)
+			}
+			if p.Stale {
+				line += " (stale)"
+			}
+			tip = append(tip, line)
+		}
+	}
+	if t := snap.Tokens; t.Today.Total > 0 || t.Week.Total > 0 {
+		tip = append(tip, fmt.Sprintf("tokens: %s today, %s in 7 days",
+			formatTokenCount(t.Today.Total), formatTokenCount(t.Week.Total)))
+	}
+	l.tooltip = strings.Join(tip, "\n")
+	return l
+}
+
+// statusOutputs lists the output formats writeStatusLine supports.
+var statusOutputs = []string{"plain", "waybar", "i3blocks", "polybar", "tmux"}
+
+// writeStatusLine writes the line in the given output format, one of
+// statusOutputs.
+func writeStatusLine(w io.Writer, l statusLine, output string) error {
+	var err error
+	switch output {
+	case "plain":
+		_, err = fmt.Fprintln(w, l.plain())
+	case "waybar":
+		err = json.NewEncoder(w).Encode(struct {
+			Text       string `json:"text"`
+			Tooltip    string `json:"tooltip"`
+			Class      string `json:"class"`
+			Percentage int    `json:"percentage"`
+		}{l.plain(), l.tooltip, l.level.String(), int(l.percent + 0.5)})
+	case "i3blocks":
+		// full text, short text, color
+		_, err = fmt.Fprintf(w, "%s\n%s\n%s\n", l.plain(), l.plain(), l.level.Color())
+	case "polybar":
+		_, err = fmt.Fprintln(w, l.colored(func(color, text string) string {
+			return "%{F" + color + "}" + text + "%{F-}"
+		}, func(text string) string {
+			return text
+		}))
+	case "tmux":
+		_, err = fmt.Fprintln(w, l.colored(func(color, text string) string {
+			return "#[fg=" + color + "]" + text + "#[default]"
+		}, func(text string) string {
+			return strings.ReplaceAll(text, "#", "##")
+		}))
+	default:
+		return fmt.Errorf("unknown output %q", output)
+	}
+	return err
+}
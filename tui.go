@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"math"
+	"slices"
 	"strings"
 	"time"
 
@@ -10,39 +12,39 @@ import (
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 // messages
 
-type usageFetchedMsg struct {
-	usage *UsageResponse
-	err   error
+// Fetch results carry the generation of the refresh that started them, so
+// results of a superseded refresh are dropped.
+type limitsFetchedMsg struct {
+	gen      int
+	provider string
+	limits   *RateLimits
+	err      error
 }
 
-type codexFetchedMsg struct {
-	usage *CodexUsage
-	err   error
-}
+type tickMsg time.Time
 
-type kimiFetchedMsg struct {
-	today TokenStats
-	week  TokenStats
+type usageFetchedMsg struct {
+	gen   int
+	usage *Aggregator
+	year  int // month of the "month" window
+	month time.Month
+	days  []time.Time // days of the chart windows
 	err   error
 }
 
-type tickMsg time.Time
-
-type tokensFetchedMsg struct {
-	today TokenStats
-	week  TokenStats
-	err   error
+// filesChangedMsg lists the session files written to since the last one.
+type filesChangedMsg struct {
+	paths []string
 }
 
-type calendarFetchedMsg struct {
-	data  DailyTokenStats
-	year  int
-	month time.Month
-	err   error
+// filesRescannedMsg carries the usage of rescanned session files, by path.
+type filesRescannedMsg struct {
+	events map[string][]UsageEvent
 }
 
 // styles
@@ -78,46 +80,80 @@ var (
 
 // model
 
-type model struct {
-	usage     *UsageResponse
+// providerSection holds the TUI state of one registered provider.
+type providerSection struct {
+	provider  Provider
+	limits    *RateLimits
 	err       error
 	lastFetch time.Time
 	stale     bool
+	bars      map[string]progress.Model // keyed by LimitWindow.Key
+}
 
-	sessionBar progress.Model
-	weeklyBar  progress.Model
-	opusBar    progress.Model
-	spinner    spinner.Model
+// hasLimits reports whether the provider returned any rate-limit windows.
+func (s providerSection) hasLimits() bool {
+	return s.limits != nil && len(s.limits.Windows) > 0
+}
 
-	// Codex
-	codexUsage      *CodexUsage
-	codexErr        error
-	codexSessionBar progress.Model
-	codexWeeklyBar  progress.Model
+// Aggregation windows filled by fetchUsageCmd and kept current by the
+// usage watcher.
+const (
+	windowToday    = "today"
+	windowWeek     = "7d"
+	windowMonth    = "month"
+	windowSessions = "sessions"
+)
 
-	// Kimi (token counts only - no rate limits stored locally)
-	kimiTokensToday TokenStats
-	kimiTokens7d    TokenStats
-	kimiErr         error
+// breakdownView selects the optional table shown under the token totals.
+type breakdownView int
+
+const (
+	breakdownNone breakdownView = iota
+	breakdownModels
+	breakdownProjects
+)
+
+type model struct {
+	sections []providerSection // one per registered provider, in order
+	spinner  spinner.Model
+	barWidth int
+
+	pending int // limit fetches still in flight
+	loading bool
+
+	// ctx scopes the fetches of the current refresh, generation gen.
+	// Starting a new refresh or quitting cancels it.
+	ctx    context.Context
+	cancel context.CancelFunc
+	gen    int
 
-	loading     bool
 	width       int
 	height      int
-	token       string
-	subType     string
 	lastRefresh time.Time // debounce
 
-	tokensToday TokenStats
-	tokens7d    TokenStats
-	tokensErr   error
+	breakdown breakdownView
+
+	usage   *Aggregator   // result of the last usage scan
+	watcher *usageWatcher // nil if file watching is unavailable
+
+	showSessions  bool
+	sessions      []Session
+	sessionSort   sessionSort
+	sessionCursor int
 
 	showCalendar  bool
 	calendarData  DailyTokenStats
 	calendarYear  int
 	calendarMonth time.Month
 
+	history     []historySample // recorded utilization, oldest first
+	showChart   bool
+	chartDays   []time.Time // days of the chart windows of the last scan
+	chartCursor int
+
 	// Config for provider visibility
-	config Config
+	config  Config
+	pricing Pricing
 }
 
 // narrow returns true when the terminal is too tight for the full layout
@@ -159,38 +195,41 @@ func (m model) borderStyle() lipgloss.Style {
 	return s.Padding(0, 2)
 }
 
-func newModel(token, subType string, cfg Config) model {
+func newModel(cfg Config) model {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("99"))
 
-	barWidth := 30
+	sections := make([]providerSection, len(providers))
+	for i, p := range providers {
+		sections[i] = providerSection{
+			provider: p,
+			bars:     make(map[string]progress.Model),
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// without a watcher, token counts only update on the periodic refresh
+	watcher, _ := newUsageWatcher(providers)
 
 	return model{
-		sessionBar:      newBar(barWidth),
-		weeklyBar:       newBar(barWidth),
-		opusBar:         newBar(barWidth),
-		codexSessionBar: newCodexBar(barWidth),
-		codexWeeklyBar:  newCodexBar(barWidth),
-		spinner:         s,
-		loading:         true,
-		token:           token,
-		subType:         subType,
-		config:          cfg,
+		sections: sections,
+		spinner:  s,
+		barWidth: 30,
+		pending:  len(providers),
+		loading:  true,
+		ctx:      ctx,
+		cancel:   cancel,
+		gen:      1,
+		usage:    newAggregator(),
+		watcher:  watcher,
+		config:   cfg,
+		pricing:  cfg.PricingTable(),
 	}
 }
 
 func newBar(width int) progress.Model {
-	// HP bar: red at low, green at high
-	p := progress.New(
-		progress.WithScaledGradient("#FF6347", "#76EEC6"),
-		progress.WithWidth(width),
-		progress.WithoutPercentage(),
-	)
-	return p
-}
-
-func newCodexBar(width int) progress.Model {
 	// HP bar: green at full health, red when depleted
 	p := progress.New(
 		progress.WithScaledGradient("#FF6347", "#76EEC6"),
@@ -201,43 +240,49 @@ func newCodexBar(width int) progress.Model {
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Batch(
-		m.spinner.Tick,
-		fetchCmd(m.token),
-		fetchCodexCmd(),
-		fetchKimiCmd(),
-		fetchTokensCmd(),
-		tickCmd(),
-	)
+	cmds := []tea.Cmd{m.spinner.Tick, m.fetchAllCmd(), tickCmd(), loadHistoryCmd()}
+	if m.watcher != nil {
+		cmds = append(cmds, m.watcher.Wait())
+	}
+	return tea.Batch(cmds...)
 }
 
-func fetchCmd(token string) tea.Cmd {
-	return func() tea.Msg {
-		usage, err := fetchUsage(token)
-		return usageFetchedMsg{usage: usage, err: err}
+// fetchAllCmd fetches rate limits for every provider and scans their
+// token usage in one pass.
+func (m model) fetchAllCmd() tea.Cmd {
+	cmds := []tea.Cmd{fetchUsageCmd(m.ctx, m.gen, providers)}
+	for _, p := range providers {
+		cmds = append(cmds, fetchLimitsCmd(m.ctx, m.gen, p))
 	}
+	return tea.Batch(cmds...)
+}
+
+// refresh cancels the fetches still in flight and starts a new round.
+func (m *model) refresh() tea.Cmd {
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	m.gen++
+	m.loading = true
+	m.pending = len(providers)
+	return tea.Batch(m.spinner.Tick, m.fetchAllCmd())
 }
 
-func fetchCodexCmd() tea.Cmd {
+func fetchLimitsCmd(ctx context.Context, gen int, p Provider) tea.Cmd {
 	return func() tea.Msg {
-		usage, err := fetchCodexUsage()
-		return codexFetchedMsg{usage: usage, err: err}
+		limits, err := p.FetchLimits(ctx)
+		if limits != nil {
+			recordLimits(p.Name(), limits)
+		}
+		return limitsFetchedMsg{gen: gen, provider: p.Name(), limits: limits, err: err}
 	}
 }
 
-func fetchKimiCmd() tea.Cmd {
+// rescanFilesCmd rescans the given session files for the windows of the
+// current usage scan. Only the bytes appended since the last scan are
+// parsed.
+func rescanFilesCmd(ctx context.Context, paths []string, since time.Time) tea.Cmd {
 	return func() tea.Msg {
-		now := time.Now()
-		startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-		today, err := scanKimiTokens(startOfDay)
-		if err != nil {
-			return kimiFetchedMsg{err: err}
-		}
-		week, err := scanKimiTokens(now.AddDate(0, 0, -7))
-		if err != nil {
-			return kimiFetchedMsg{today: today, err: err}
-		}
-		return kimiFetchedMsg{today: today, week: week}
+		return filesRescannedMsg{events: rescanFiles(ctx, providers, paths, since)}
 	}
 }
 
@@ -247,156 +292,255 @@ func tickCmd() tea.Cmd {
 	})
 }
 
-func fetchTokensCmd() tea.Cmd {
+// fetchUsageCmd scans the session files of every provider once, filling
+// the today, 7-day, month and session windows from the same pass.
+func fetchUsageCmd(ctx context.Context, gen int, ps []Provider) tea.Cmd {
 	return func() tea.Msg {
 		now := time.Now()
 		startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-		today, err := scanAllTokens(startOfDay)
-		if err != nil {
-			return tokensFetchedMsg{err: err}
-		}
-		week, err := scanAllTokens(now.AddDate(0, 0, -7))
-		if err != nil {
-			return tokensFetchedMsg{today: today, err: err}
+		startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		weekAgo := now.AddDate(0, 0, -7)
+
+		usage := newAggregator()
+		usage.Window(windowToday, Window{Since: startOfDay})
+		usage.Window(windowWeek, Window{Since: weekAgo})
+		usage.DailyWindow(windowMonth, Window{Since: startOfMonth})
+		usage.SessionWindow(windowSessions, Window{Since: weekAgo})
+		days := lastDays(now, chartDays)
+		for _, day := range days {
+			usage.Window(chartWindow(day), Window{Since: day, Until: day.AddDate(0, 0, 1)})
 		}
-		return tokensFetchedMsg{today: today, week: week}
+		err := scanUsage(ctx, ps, usage)
+		return usageFetchedMsg{gen: gen, usage: usage, year: now.Year(), month: now.Month(), days: days, err: err}
 	}
 }
 
-func fetchCalendarCmd(year int, month time.Month) tea.Cmd {
-	return func() tea.Msg {
-		data, err := scanAllTokensByDay(year, month)
-		return calendarFetchedMsg{data: data, year: year, month: month, err: err}
+// visible reports whether a section has anything to render: bars for
+// providers with rate limits, token rows for providers without, or the
+// error of an account whose limits couldn't be fetched. Providers without
+// a login, such as an uninstalled Codex, stay hidden on error.
+func (m model) visible(s providerSection) bool {
+	if s.hasLimits() {
+		return true
+	}
+	if _, ok := s.provider.(loginProvider); ok && s.err != nil {
+		return true
 	}
+	tokens := func(window string) int {
+		return m.usage.Tokens(window, []Provider{s.provider}).Sum().Total()
+	}
+	return s.limits == nil && (tokens(windowToday) > 0 || tokens(windowWeek) > 0)
+}
+
+// applyUsage derives the session list and calendar of the enabled
+// providers from the last usage scan.
+func (m *model) applyUsage() {
+	enabled := enabledProviders(m.config)
+	m.sessions = m.usage.Sessions(windowSessions, enabled)
+	sortSessions(m.sessions, m.sessionSort, m.pricing)
+	m.sessionCursor = min(m.sessionCursor, max(0, len(m.sessions)-1))
+	m.calendarData = m.usage.Daily(windowMonth, enabled)
+}
+
+// section returns the index of the named provider's section, or -1.
+func (m model) section(name string) int {
+	for i, s := range m.sections {
+		if s.provider.Name() == name {
+			return i
+		}
+	}
+	return -1
 }
 
 func (m *model) resizeBars() {
 	cw := m.contentWidth()
-	// bar = content - label - " " - percent(6)
+	// bar = content - label - " " - percent(6) [- " " - sparkline]
 	barWidth := cw - m.labelWidth() - 7
-	barWidth = max(8, min(barWidth, 30))
-	m.sessionBar.Width = barWidth
-	m.weeklyBar.Width = barWidth
-	m.opusBar.Width = barWidth
-	m.codexSessionBar.Width = barWidth
-	m.codexWeeklyBar.Width = barWidth
+	if m.showSparklines() {
+		barWidth -= sparkWidth + 1
+	}
+	m.barWidth = max(8, min(barWidth, 30))
+	for _, s := range m.sections {
+		for key, bar := range s.bars {
+			bar.Width = m.barWidth
+			s.bars[key] = bar
+		}
+	}
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 
 	case tea.KeyMsg:
-		switch msg.String() {
+		switch key := msg.String(); key {
 		case "q", "ctrl+c":
+			m.cancel()
+			if m.watcher != nil {
+				m.watcher.Close()
+			}
 			return m, tea.Quit
 		case "r":
 			if time.Since(m.lastRefresh) < 10*time.Second {
 				return m, nil
 			}
-			m.loading = true
 			m.lastRefresh = time.Now()
-			cmds := []tea.Cmd{m.spinner.Tick, fetchCmd(m.token), fetchCodexCmd(), fetchKimiCmd(), fetchTokensCmd()}
-			if m.showCalendar {
-				cmds = append(cmds, fetchCalendarCmd(m.calendarYear, m.calendarMonth))
+			return m, m.refresh()
+		case "m":
+			m.breakdown = m.toggleBreakdown(breakdownModels)
+			return m, nil
+		case "p":
+			m.breakdown = m.toggleBreakdown(breakdownProjects)
+			return m, nil
+		case "s":
+			m.showSessions = !m.showSessions
+			m.showCalendar = false
+			m.showChart = false
+			return m, nil
+		case "t":
+			m.showChart = !m.showChart
+			m.showCalendar = false
+			m.showSessions = false
+			return m, nil
+		case "left", "h":
+			if m.showChart && m.chartCursor > 0 {
+				m.chartCursor--
 			}
-			return m, tea.Batch(cmds...)
-		case "c":
-			m.showCalendar = !m.showCalendar
-			if m.showCalendar && m.calendarData == nil {
-				now := time.Now()
-				m.calendarYear = now.Year()
-				m.calendarMonth = now.Month()
-				return m, fetchCalendarCmd(now.Year(), now.Month())
+			return m, nil
+		case "right", "l":
+			if m.showChart && m.chartCursor < len(m.chartDays)-1 {
+				m.chartCursor++
 			}
 			return m, nil
-		case "1":
-			m.config.Providers.Claude = !m.config.Providers.Claude
-			m.config.Save()
+		case "o":
+			if m.showSessions {
+				m.sessionSort = (m.sessionSort + 1) % numSessionSorts
+				sortSessions(m.sessions, m.sessionSort, m.pricing)
+				m.sessionCursor = 0
+			}
 			return m, nil
-		case "2":
-			m.config.Providers.Codex = !m.config.Providers.Codex
-			m.config.Save()
+		case "up", "k":
+			if m.showSessions && m.sessionCursor > 0 {
+				m.sessionCursor--
+			}
 			return m, nil
-		case "3":
-			m.config.Providers.Kimi = !m.config.Providers.Kimi
-			m.config.Save()
+		case "down", "j":
+			if m.showSessions && m.sessionCursor < len(m.sessions)-1 {
+				m.sessionCursor++
+			}
+			return m, nil
+		case "home", "g":
+			if m.showSessions {
+				m.sessionCursor = 0
+			}
+			if m.showChart {
+				m.chartCursor = 0
+			}
+			return m, nil
+		case "end", "G":
+			if m.showSessions {
+				m.sessionCursor = max(0, len(m.sessions)-1)
+			}
+			if m.showChart {
+				m.chartCursor = max(0, len(m.chartDays)-1)
+			}
+			return m, nil
+		case "c":
+			m.showCalendar = !m.showCalendar
+			m.showSessions = false
+			m.showChart = false
+			return m, nil
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			if i := int(key[0] - '1'); i < len(providers) {
+				m.config.ToggleProvider(providers[i].Name())
+				m.config.Save()
+				if m.sessions != nil {
+					m.applyUsage()
+				}
+			}
 			return m, nil
 		}
 
-	case usageFetchedMsg:
-		m.loading = false
+	case limitsFetchedMsg:
+		if msg.gen != m.gen {
+			return m, nil
+		}
+		m.pending = max(0, m.pending-1)
+		m.loading = m.pending > 0
+		i := m.section(msg.provider)
+		if i < 0 {
+			return m, nil
+		}
+		s := &m.sections[i]
 		if msg.err != nil {
-			if m.usage != nil {
-				m.stale = true
-				m.err = msg.err
-			} else {
-				m.err = msg.err
-			}
+			s.err = msg.err
+			s.stale = s.limits != nil
+			return m, nil
+		}
+		s.limits = msg.limits
+		s.err = nil
+		s.stale = false
+		s.lastFetch = time.Now()
+		if s.limits == nil {
 			return m, nil
 		}
-		m.usage = msg.usage
-		m.err = nil
-		m.stale = false
-		m.lastFetch = time.Now()
+		m.addHistory(msg.provider, s.limits)
 
 		var cmds []tea.Cmd
-		if m.usage.FiveHour != nil {
-			cmds = append(cmds, m.sessionBar.SetPercent((100-m.usage.FiveHour.Utilization)/100))
-		}
-		if m.usage.SevenDay != nil {
-			cmds = append(cmds, m.weeklyBar.SetPercent((100-m.usage.SevenDay.Utilization)/100))
-		}
-		if m.usage.SevenDayOpus != nil {
-			cmds = append(cmds, m.opusBar.SetPercent((100-m.usage.SevenDayOpus.Utilization)/100))
+		for _, w := range s.limits.Windows {
+			bar, ok := s.bars[w.Key]
+			if !ok {
+				bar = newBar(m.barWidth)
+			}
+			cmds = append(cmds, bar.SetPercent((100-w.UsedPercent)/100))
+			s.bars[w.Key] = bar
 		}
 		return m, tea.Batch(cmds...)
 
-	case codexFetchedMsg:
-		if msg.err == nil {
-			m.codexUsage = msg.usage
-			var cmds []tea.Cmd
-			if m.codexUsage.Primary != nil {
-				cmds = append(cmds, m.codexSessionBar.SetPercent((100-m.codexUsage.Primary.UsedPercent)/100))
-			}
-			if m.codexUsage.Secondary != nil {
-				cmds = append(cmds, m.codexWeeklyBar.SetPercent((100-m.codexUsage.Secondary.UsedPercent)/100))
+	case usageFetchedMsg:
+		if msg.gen != m.gen {
+			return m, nil
+		}
+		// a provider that failed to scan just contributes nothing
+		if msg.usage != nil {
+			m.usage = msg.usage
+			m.calendarYear = msg.year
+			m.calendarMonth = msg.month
+			if m.chartDays == nil || m.chartCursor == len(m.chartDays)-1 {
+				m.chartCursor = len(msg.days) - 1 // follow today
 			}
-			return m, tea.Batch(cmds...)
+			m.chartDays = msg.days
+			m.applyUsage()
 		}
-		m.codexErr = msg.err
 		return m, nil
 
-	case kimiFetchedMsg:
-		if msg.err == nil {
-			m.kimiTokensToday = msg.today
-			m.kimiTokens7d = msg.week
+	case historyLoadedMsg:
+		// keep samples fetched while the history was being read
+		loaded := msg.samples
+		for _, s := range m.history {
+			if !slices.ContainsFunc(loaded, func(l historySample) bool { return l.key() == s.key() }) {
+				loaded = append(loaded, s)
+			}
 		}
-		m.kimiErr = msg.err
+		m.history = loaded
 		return m, nil
 
-	case tokensFetchedMsg:
-		if msg.err == nil {
-			m.tokensToday = msg.today
-			m.tokens7d = msg.week
+	case filesChangedMsg:
+		cmds := []tea.Cmd{m.watcher.Wait()}
+		// before the first scan completes, that scan picks the files up
+		if since := m.usage.Since(); !since.IsZero() {
+			cmds = append(cmds, rescanFilesCmd(m.watcher.ctx, msg.paths, since))
 		}
-		m.tokensErr = msg.err
-		return m, nil
+		return m, tea.Batch(cmds...)
 
-	case calendarFetchedMsg:
-		if msg.err == nil {
-			m.calendarData = msg.data
-			m.calendarYear = msg.year
-			m.calendarMonth = msg.month
+	case filesRescannedMsg:
+		for path, events := range msg.events {
+			m.usage.Replace(path, events)
 		}
+		m.applyUsage()
 		return m, nil
 
 	case tickMsg:
-		m.loading = true
-		cmds := []tea.Cmd{m.spinner.Tick, fetchCmd(m.token), fetchCodexCmd(), fetchKimiCmd(), fetchTokensCmd(), tickCmd()}
-		if m.showCalendar {
-			cmds = append(cmds, fetchCalendarCmd(m.calendarYear, m.calendarMonth))
-		}
-		return m, tea.Batch(cmds...)
+		return m, tea.Batch(m.refresh(), tickCmd())
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -411,27 +555,13 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case progress.FrameMsg:
 		var cmds []tea.Cmd
-
-		pm, c := m.sessionBar.Update(msg)
-		m.sessionBar = pm.(progress.Model)
-		cmds = append(cmds, c)
-
-		pm, c = m.weeklyBar.Update(msg)
-		m.weeklyBar = pm.(progress.Model)
-		cmds = append(cmds, c)
-
-		pm, c = m.opusBar.Update(msg)
-		m.opusBar = pm.(progress.Model)
-		cmds = append(cmds, c)
-
-		pm, c = m.codexSessionBar.Update(msg)
-		m.codexSessionBar = pm.(progress.Model)
-		cmds = append(cmds, c)
-
-		pm, c = m.codexWeeklyBar.Update(msg)
-		m.codexWeeklyBar = pm.(progress.Model)
-		cmds = append(cmds, c)
-
+		for _, s := range m.sections {
+			for key, bar := range s.bars {
+				pm, c := bar.Update(msg)
+				s.bars[key] = pm.(progress.Model)
+				cmds = append(cmds, c)
+			}
+		}
 		return m, tea.Batch(cmds...)
 	}
 
@@ -441,25 +571,45 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m model) View() string {
 	var b strings.Builder
 
+	var visible []providerSection
+	var plan string
+	var lastFetch time.Time
+	stale := false
+	for _, s := range m.sections {
+		if !m.config.Enabled(s.provider.Name()) {
+			continue
+		}
+		if m.visible(s) {
+			visible = append(visible, s)
+		}
+		if plan == "" && s.limits != nil {
+			plan = s.limits.Plan
+		}
+		if s.lastFetch.After(lastFetch) {
+			lastFetch = s.lastFetch
+		}
+		stale = stale || s.stale
+	}
+
 	// title row
 	cw := m.contentWidth()
 	title := titleStyle.Render("llm-usage")
 	if m.loading {
 		title += "  " + m.spinner.View()
-	} else if m.stale {
+	} else if stale {
 		title += "  " + staleStyle.Render("stale")
 	}
 
 	// right side: subscription type + last updated
 	right := ""
-	if m.subType != "" {
-		right += strings.ToUpper(m.subType[:1]) + m.subType[1:]
+	if plan != "" {
+		right += strings.ToUpper(plan[:1]) + plan[1:]
 	}
-	if !m.lastFetch.IsZero() {
+	if !lastFetch.IsZero() {
 		if right != "" {
 			right += " • "
 		}
-		right += m.lastFetch.Format("15:04")
+		right += lastFetch.Format("15:04")
 	}
 	if right != "" {
 		titleRow := title + footerStyle.Render(strings.Repeat(" ", max(1, cw-lipgloss.Width(title)-lipgloss.Width(right)))+right)
@@ -468,10 +618,14 @@ func (m model) View() string {
 		b.WriteString(title + "\n")
 	}
 
-	// error only (no data yet)
-	if m.err != nil && m.usage == nil && m.codexUsage == nil {
-		b.WriteString(errorStyle.Render("  "+m.err.Error()) + "\n")
-		return m.borderStyle().Render(b.String())
+	// error only (no data yet): show the first provider error
+	if len(visible) == 0 {
+		for _, s := range m.sections {
+			if s.err != nil && m.config.Enabled(s.provider.Name()) {
+				b.WriteString(errorStyle.Render("  "+s.err.Error()) + "\n")
+				return m.borderStyle().Render(b.String())
+			}
+		}
 	}
 
 	if m.showCalendar {
@@ -479,147 +633,178 @@ func (m model) View() string {
 		return m.borderStyle().Render(b.String())
 	}
 
-	narrow := m.narrow()
-	lw := m.labelWidth()
-	hasCodex := m.codexUsage != nil && m.config.Providers.Codex
-	hasClaude := m.usage != nil && m.config.Providers.Claude
-	hasKimi := (m.kimiTokensToday.Total() > 0 || m.kimiTokens7d.Total() > 0) && m.config.Providers.Kimi
-
-	// Claude section
-	if hasClaude {
-		if hasCodex || hasKimi {
-			b.WriteString(sectionStyle.Render("Claude") + "\n")
-		}
-		if m.usage.FiveHour != nil {
-			label := "Session (5h)"
-			if narrow {
-				label = "5h"
-			}
-			remaining := &UsageBucket{Utilization: 100 - m.usage.FiveHour.Utilization}
-			b.WriteString(m.renderBar(label, m.sessionBar, remaining, lw))
-		}
-		if m.usage.SevenDay != nil {
-			label := "Weekly (7d)"
-			if narrow {
-				label = "7d"
-			}
-			remaining := &UsageBucket{Utilization: 100 - m.usage.SevenDay.Utilization}
-			b.WriteString(m.renderBar(label, m.weeklyBar, remaining, lw))
-		}
-		if m.usage.SevenDayOpus != nil {
-			label := "Opus (7d)"
-			if narrow {
-				label = "Opus"
-			}
-			remaining := &UsageBucket{Utilization: 100 - m.usage.SevenDayOpus.Utilization}
-			b.WriteString(m.renderBar(label, m.opusBar, remaining, lw))
-		}
-		b.WriteString(m.renderResets())
+	if m.showSessions {
+		b.WriteString(m.renderSessions())
+		return m.borderStyle().Render(b.String())
 	}
 
-	// Codex section
-	if hasCodex {
-		if hasClaude {
-			b.WriteString("\n")
-		}
-		b.WriteString(sectionStyle.Render("Codex") + "\n")
-		if m.codexUsage.Primary != nil {
-			label := "Session (5h)"
-			if narrow {
-				label = "5h"
-			}
-			remaining := 100 - m.codexUsage.Primary.UsedPercent
-			bucket := &UsageBucket{Utilization: remaining}
-			b.WriteString(m.renderBar(label, m.codexSessionBar, bucket, lw))
-		}
-		if m.codexUsage.Secondary != nil {
-			label := "Weekly (7d)"
-			if narrow {
-				label = "7d"
-			}
-			remaining := 100 - m.codexUsage.Secondary.UsedPercent
-			bucket := &UsageBucket{Utilization: remaining}
-			b.WriteString(m.renderBar(label, m.codexWeeklyBar, bucket, lw))
-		}
-		b.WriteString(m.renderCodexResets())
+	if m.showChart {
+		b.WriteString(m.renderChart())
+		return m.borderStyle().Render(b.String())
 	}
 
-	// Kimi section (token counts only)
-	if hasKimi {
-		if hasClaude || hasCodex {
+	for i, s := range visible {
+		if i > 0 {
 			b.WriteString("\n")
 		}
-		b.WriteString(sectionStyle.Render("Kimi") + "\n")
-		b.WriteString(m.renderKimiTokenSection())
+		if len(visible) > 1 {
+			b.WriteString(sectionStyle.Render(s.provider.Title()) + "\n")
+		}
+		if s.hasLimits() {
+			b.WriteString(m.renderLimits(s))
+			continue
+		}
+		if s.err != nil {
+			b.WriteString(errorStyle.Render("  "+s.err.Error()) + "\n")
+		}
+		// providers without rate limits show their own token counts
+		ps := []Provider{s.provider}
+		today, week := m.usage.Tokens(windowToday, ps).ByModel(), m.usage.Tokens(windowWeek, ps).ByModel()
+		if today.Sum().Total() > 0 || week.Sum().Total() > 0 {
+			b.WriteString(m.renderTokenRows(today, week))
+		}
 	}
 
 	// aggregated token counts (all enabled providers)
-	if m.config.AnyEnabled() && (m.tokensToday.Total() > 0 || m.tokens7d.Total() > 0) {
+	enabled := enabledProviders(m.config)
+	today := m.usage.Tokens(windowToday, enabled)
+	week := m.usage.Tokens(windowWeek, enabled)
+	month := m.usage.Tokens(windowMonth, enabled)
+	if today.Sum().Total() > 0 || week.Sum().Total() > 0 {
 		b.WriteString("\n")
-		b.WriteString(m.renderTokenSection())
+		switch m.breakdown {
+		case breakdownModels:
+			b.WriteString(m.renderModelTable(today.ByModel(), week.ByModel()))
+		case breakdownProjects:
+			b.WriteString(m.renderProjectTable(today.ByProject(), week.ByProject(), month.ByProject()))
+		default:
+			b.WriteString(m.renderTokenRows(today.ByModel(), week.ByModel()))
+		}
 	}
 
-	// stale error
-	if m.stale && m.err != nil {
-		b.WriteString(staleStyle.Render("  "+m.err.Error()) + "\n\n")
+	// stale errors
+	for _, s := range visible {
+		if s.stale && s.err != nil {
+			b.WriteString(staleStyle.Render("  "+s.err.Error()) + "\n\n")
+		}
 	}
 
 	// footer hint with provider toggles
 	providerHints := []string{
 		"[c] calendar",
+		"[s] sessions",
+		"[t] trend",
+		"[m] models",
+		"[p] projects",
 	}
-	if m.config.Providers.Claude {
-		providerHints = append(providerHints, "[1] Claude ✓")
-	} else {
-		providerHints = append(providerHints, "[1] Claude ✗")
-	}
-	if m.config.Providers.Codex {
-		providerHints = append(providerHints, "[2] Codex ✓")
-	} else {
-		providerHints = append(providerHints, "[2] Codex ✗")
+	for i, p := range providers {
+		mark := "✗"
+		if m.config.Enabled(p.Name()) {
+			mark = "✓"
+		}
+		providerHints = append(providerHints, fmt.Sprintf("[%d] %s %s", i+1, p.Title(), mark))
 	}
-	if m.config.Providers.Kimi {
-		providerHints = append(providerHints, "[3] Kimi ✓")
-	} else {
-		providerHints = append(providerHints, "[3] Kimi ✗")
+	// non-breaking spaces keep each hint on one line when the footer wraps
+	for i, h := range providerHints {
+		providerHints[i] = strings.ReplaceAll(h, " ", "\u00a0")
 	}
-	b.WriteString(footerStyle.Render("  "+joinWith(providerHints, "  ")) + "\n")
+	b.WriteString(footerStyle.Width(cw).Render("  "+joinWith(providerHints, "  ")) + "\n")
 
 	return m.borderStyle().Render(b.String())
 }
 
-func (m model) renderBar(label string, bar progress.Model, bucket *UsageBucket, labelWidth int) string {
-	pct := bucket.Utilization
-	pctStr := percentStyle.Render(fmt.Sprintf("%.0f%%", pct))
-	labelStr := lipgloss.NewStyle().Width(labelWidth).Foreground(labelColor).Render(label)
-	return labelStr + bar.View() + " " + pctStr + "\n"
+// renderLimits renders one bar per rate-limit window plus the reset line.
+func (m model) renderLimits(s providerSection) string {
+	var b strings.Builder
+	lw := m.labelWidth()
+	now := time.Now()
+	for _, w := range s.limits.Windows {
+		label := w.Label
+		if m.narrow() {
+			label = w.Short
+		}
+		spark := ""
+		if m.showSparklines() {
+			spark = m.renderSparkline(s.provider.Name(), w, now)
+		}
+		bar := s.bars[w.Key].View()
+		if elapsed, ok := windowElapsed(w, now); ok {
+			bar = paceMarker(bar, elapsed)
+		}
+		b.WriteString(m.renderBar(label, bar, 100-w.UsedPercent, lw, spark))
+	}
+	b.WriteString(m.renderResets(s))
+	return b.String()
 }
 
-func (m model) renderResets() string {
-	dim := lipgloss.NewStyle().Foreground(resetColor)
-	var parts []string
-	if m.usage.FiveHour != nil && m.usage.FiveHour.ResetsAt != nil {
-		parts = append(parts, "5h: "+formatReset(*m.usage.FiveHour.ResetsAt))
+// renderBar renders a labeled bar of the percent remaining, followed by a
+// sparkline if spark isn't empty.
+func (m model) renderBar(label string, bar string, pct float64, labelWidth int, spark string) string {
+	style := percentStyle
+	if level := levelFor(100 - pct); level != levelOK {
+		style = style.Foreground(lipgloss.Color(level.Color()))
 	}
-	if m.usage.SevenDay != nil && m.usage.SevenDay.ResetsAt != nil {
-		parts = append(parts, "7d: "+formatReset(*m.usage.SevenDay.ResetsAt))
+	pctStr := style.Render(fmt.Sprintf("%.0f%%", pct))
+	labelStr := lipgloss.NewStyle().Width(labelWidth).Foreground(labelColor).Render(label)
+	if spark != "" {
+		pctStr += " " + spark
 	}
-	if len(parts) == 0 {
-		return ""
+	return labelStr + bar + " " + pctStr + "\n"
+}
+
+// paceMarker draws a marker on a rendered bar of the percent remaining
+// where the bar would end at an even pace, elapsed percent into the
+// window. A bar that stops short of it is ahead of pace.
+func paceMarker(bar string, elapsed float64) string {
+	width := ansi.StringWidth(bar)
+	if width == 0 {
+		return bar
 	}
-	return dim.Render(strings.Join(parts, "  ")) + "\n"
+	col := min(width-1, int(math.Round((100-elapsed)/100*float64(width))))
+	marker := lipgloss.NewStyle().Foreground(labelColor).Render("┃")
+	return ansi.Cut(bar, 0, col) + marker + ansi.Cut(bar, col+1, width)
 }
 
-func (m model) renderCodexResets() string {
+// renderResets renders when each window resets on one line or, once
+// there is a pace or forecast to show, a line per window with them.
+func (m model) renderResets(s providerSection) string {
 	dim := lipgloss.NewStyle().Foreground(resetColor)
-	var parts []string
-	if m.codexUsage.Primary != nil && m.codexUsage.Primary.ResetsAt > 0 {
-		t := m.codexUsage.Primary.ResetsAtTime()
-		parts = append(parts, "5h: "+formatReset(t.Format(time.RFC3339)))
+	now := time.Now()
+	asOf := s.limits.AsOf
+	if asOf.IsZero() {
+		asOf = now
 	}
-	if m.codexUsage.Secondary != nil && m.codexUsage.Secondary.ResetsAt > 0 {
-		t := m.codexUsage.Secondary.ResetsAtTime()
-		parts = append(parts, "7d: "+formatReset(t.Format(time.RFC3339)))
+	var parts, forecasts []string
+	forecasting := false
+	for _, w := range s.limits.Windows {
+		part := w.Key + ":"
+		if !w.ResetsAt.IsZero() {
+			part += " " + formatReset(w.ResetsAt)
+		}
+		line := part
+		elapsed, paced := windowElapsed(w, now)
+		if paced {
+			line += " · " + formatPace(w.UsedPercent, elapsed)
+		}
+		f, ok := forecastWindow(m.history, s.provider.Name(), w, asOf)
+		switch {
+		case !ok && !paced && w.ResetsAt.IsZero():
+		case !ok:
+			forecasts = append(forecasts, dim.Render(line))
+		case f.OnTrack():
+			forecasts = append(forecasts, dim.Render(line+" · on track"))
+		default:
+			level := max(levelWarning, levelFor(w.UsedPercent))
+			forecasts = append(forecasts, dim.Render(line+" · ")+
+				lipgloss.NewStyle().Foreground(lipgloss.Color(level.Color())).Render(formatExhaustion(f.ExhaustsAt, now)))
+		}
+		forecasting = forecasting || ok || paced
+		if !w.ResetsAt.IsZero() {
+			parts = append(parts, part)
+		}
+	}
+	if forecasting {
+		return strings.Join(forecasts, "\n") + "\n"
 	}
 	if len(parts) == 0 {
 		return ""
@@ -627,7 +812,7 @@ func (m model) renderCodexResets() string {
 	return dim.Render(strings.Join(parts, "  ")) + "\n"
 }
 
-func (m model) renderTokenSection() string {
+func (m model) renderTokenRows(today, week ModelTokenStats) string {
 	var b strings.Builder
 	narrow := m.narrow()
 
@@ -642,60 +827,232 @@ func (m model) renderTokenSection() string {
 	}
 
 	lw := m.labelWidth()
+	showReasoning := today.Sum().Reasoning > 0 || week.Sum().Reasoning > 0
 
-	renderRow := func(label string, stats TokenStats) string {
+	renderRow := func(label string, models ModelTokenStats) string {
+		stats := models.Sum()
 		labelStr := lipgloss.NewStyle().Width(lw).Foreground(labelColor).Render(label)
 		totalIn := stats.InputTokens + stats.CacheCreation + stats.CacheRead
 		in := valStyle.Render(formatTokenCount(totalIn))
 		out := valStyle.Render(formatTokenCount(stats.OutputTokens))
 		inLabel := dimStyle.Render(" in  ")
 		outLabel := dimStyle.Render(" out")
-		return labelStr + in + inLabel + out + outLabel + "\n"
+		return labelStr + in + inLabel + out + outLabel + m.renderReasoning(stats, showReasoning) + m.renderCost(models) + "\n"
 	}
 
-	// Use pre-computed aggregated tokens
-	if m.tokensToday.Total() > 0 {
-		b.WriteString(renderRow(todayLabel, m.tokensToday))
+	if today.Sum().Total() > 0 {
+		b.WriteString(renderRow(todayLabel, today))
 	}
-	if m.tokens7d.Total() > 0 {
-		b.WriteString(renderRow(weekLabel, m.tokens7d))
+	if week.Sum().Total() > 0 {
+		b.WriteString(renderRow(weekLabel, week))
 	}
 
 	return b.String()
 }
 
-func (m model) renderKimiTokenSection() string {
+// renderModelTable lists token usage per model for today and the last 7 days.
+func (m model) renderModelTable(today, week ModelTokenStats) string {
+	var b strings.Builder
+
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("243"))
+	valStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+
+	lw := m.labelWidth()
+
+	renderBlock := func(title string, stats ModelTokenStats) {
+		if stats.Sum().Total() == 0 {
+			return
+		}
+		b.WriteString(sectionStyle.Render(title) + "\n")
+		showReasoning := stats.Sum().Reasoning > 0
+		for _, name := range stats.Sorted() {
+			s := stats[name]
+			if s.Total() == 0 {
+				continue
+			}
+			label := ansi.Truncate(shortModelName(name), lw-1, "")
+			labelStr := lipgloss.NewStyle().Width(lw).Foreground(labelColor).Render(label)
+			totalIn := s.InputTokens + s.CacheCreation + s.CacheRead
+			in := valStyle.Render(formatTokenCount(totalIn))
+			out := valStyle.Render(formatTokenCount(s.OutputTokens))
+			cost := m.renderCost(ModelTokenStats{name: s})
+			rsn := m.renderReasoning(s, showReasoning)
+			b.WriteString(labelStr + in + dimStyle.Render(" in  ") + out + dimStyle.Render(" out") + rsn + cost + "\n")
+		}
+	}
+
+	if m.narrow() {
+		renderBlock("1d", today)
+		renderBlock("7d", week)
+	} else {
+		renderBlock("Today", today)
+		renderBlock("Last 7 days", week)
+	}
+
+	return b.String()
+}
+
+// renderProjectTable lists the projects with the most token usage this
+// month, with their today, 7-day and month totals.
+func (m model) renderProjectTable(today, week, month ProjectTokenStats) string {
+	const maxProjects = 10
+
 	var b strings.Builder
 	narrow := m.narrow()
 
 	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("243"))
 	valStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
 
-	todayLabel := "Today"
-	weekLabel := "Last 7 days"
+	lw := m.labelWidth()
+	label := func(s string) string {
+		s = ansi.Truncate(s, lw-1, "")
+		return lipgloss.NewStyle().Width(lw).Foreground(labelColor).Render(s)
+	}
+
 	if narrow {
-		todayLabel = "1d"
-		weekLabel = "7d"
+		b.WriteString(label("Project") + dimStyle.Render(fmt.Sprintf("%6s %7s", "7d", "month")) + "\n")
+	} else {
+		b.WriteString(label("Project") + dimStyle.Render(fmt.Sprintf("%6s %6s %6s %8s", "today", "7d", "month", "≈$")) + "\n")
 	}
 
-	lw := m.labelWidth()
+	for i, project := range month.Sorted() {
+		if i == maxProjects {
+			break
+		}
+		total := month[project].Sum().Total()
+		if total == 0 {
+			continue
+		}
+		var line string
+		if narrow {
+			line = fmt.Sprintf("%6s %7s",
+				formatTokenCount(week[project].Sum().Total()),
+				formatTokenCount(total))
+		} else {
+			line = fmt.Sprintf("%6s %6s %6s %8s",
+				formatTokenCount(today[project].Sum().Total()),
+				formatTokenCount(week[project].Sum().Total()),
+				formatTokenCount(total),
+				m.costCell(month[project]))
+		}
+		b.WriteString(label(shortProjectName(project)) + valStyle.Render(line) + "\n")
+	}
 
-	renderRow := func(label string, stats TokenStats) string {
-		labelStr := lipgloss.NewStyle().Width(lw).Foreground(labelColor).Render(label)
-		totalIn := stats.InputTokens + stats.CacheCreation + stats.CacheRead
-		in := valStyle.Render(formatTokenCount(totalIn))
-		out := valStyle.Render(formatTokenCount(stats.OutputTokens))
-		inLabel := dimStyle.Render(" in  ")
-		outLabel := dimStyle.Render(" out")
-		return labelStr + in + inLabel + out + outLabel + "\n"
+	return b.String()
+}
+
+// toggleBreakdown switches to v, or back to the plain totals if v is
+// already shown.
+func (m model) toggleBreakdown(v breakdownView) breakdownView {
+	if m.breakdown == v {
+		return breakdownNone
+	}
+	return v
+}
+
+// renderReasoning renders the reasoning column: the hidden reasoning
+// output counted apart from "out". It is only shown when some of the
+// usage on screen has reasoning tokens.
+func (m model) renderReasoning(s TokenStats, show bool) string {
+	if !show {
+		return ""
+	}
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("243"))
+	valStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+	return valStyle.Render("  "+formatTokenCount(s.Reasoning)) + dimStyle.Render(" rsn")
+}
+
+// renderCost renders the "$ equivalent" column: the API price of the
+// given usage. It is empty when none of the models has a known price.
+func (m model) renderCost(stats ModelTokenStats) string {
+	if !m.pricing.Priced(stats) {
+		return ""
+	}
+	costStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("243"))
+	return costStyle.Render("  ≈" + formatCost(m.pricing.Cost(stats)))
+}
+
+// costCell renders the estimated cost of stats for a table column, or "-"
+// if no model in it has a known price.
+func (m model) costCell(stats ModelTokenStats) string {
+	if !m.pricing.Priced(stats) {
+		return "-"
 	}
+	return formatCost(m.pricing.Cost(stats))
+}
 
-	if m.kimiTokensToday.Total() > 0 {
-		b.WriteString(renderRow(todayLabel, m.kimiTokensToday))
+// renderSessions renders the session browser: a scrolling list of recent
+// sessions with a detail panel for the one under the cursor.
+func (m model) renderSessions() string {
+	var b strings.Builder
+
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("243"))
+	valStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+	selStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("99"))
+
+	b.WriteString(sectionStyle.Render("Sessions (7d)") + dimStyle.Render(" by "+m.sessionSort.String()) + "\n")
+
+	if m.sessions == nil {
+		b.WriteString("  loading...\n")
+		return b.String()
+	}
+	if len(m.sessions) == 0 {
+		b.WriteString(dimStyle.Render("  no sessions") + "\n")
+		b.WriteString(footerStyle.Render("  [s] back") + "\n")
+		return b.String()
+	}
+
+	// list viewport: leave room for title, detail panel and footer
+	rows := 10
+	if m.height > 0 {
+		rows = max(3, m.height-12)
 	}
-	if m.kimiTokens7d.Total() > 0 {
-		b.WriteString(renderRow(weekLabel, m.kimiTokens7d))
+	start := 0
+	if m.sessionCursor >= rows {
+		start = m.sessionCursor - rows + 1
 	}
+	end := min(len(m.sessions), start+rows)
+
+	narrow := m.narrow()
+	for i := start; i < end; i++ {
+		s := m.sessions[i]
+		tokens := formatTokenCount(s.Tokens.Sum().Total())
+		cost := m.costCell(s.Tokens.ByModel())
+		var line string
+		if narrow {
+			line = fmt.Sprintf("%s %-8.8s %6s", s.End.Local().Format("15:04"), shortProjectName(s.Project), tokens)
+		} else {
+			line = fmt.Sprintf("%s  %-6s %-12.12s %6s %7s",
+				s.End.Local().Format("Mon 15:04"), s.Provider, shortProjectName(s.Project),
+				tokens, cost)
+		}
+		if i == m.sessionCursor {
+			b.WriteString(selStyle.Render("› "+line) + "\n")
+		} else {
+			b.WriteString(valStyle.Render("  "+line) + "\n")
+		}
+	}
+
+	// detail panel
+	s := m.sessions[m.sessionCursor]
+	t := s.Tokens.Sum()
+	b.WriteString("\n")
+	b.WriteString(valStyle.Render(fmt.Sprintf("  %s · %s", s.Provider, shortModelName(s.Model))) + "\n")
+	if s.Project != "" {
+		b.WriteString(dimStyle.Render("  "+displayPath(s.Project)) + "\n")
+	}
+	b.WriteString(dimStyle.Render(fmt.Sprintf("  %s → %s (%s)",
+		s.Start.Local().Format("Jan 2 15:04"), s.End.Local().Format("Jan 2 15:04"),
+		formatDuration(s.End.Sub(s.Start)))) + "\n")
+	detail := fmt.Sprintf("  in %s  out %s  cache %s/%s",
+		formatTokenCount(t.InputTokens), formatTokenCount(t.OutputTokens),
+		formatTokenCount(t.CacheCreation), formatTokenCount(t.CacheRead))
+	if t.Reasoning > 0 {
+		detail += "  rsn " + formatTokenCount(t.Reasoning)
+	}
+	b.WriteString(dimStyle.Render(detail) + m.renderCost(s.Tokens.ByModel()) + "\n")
+
+	b.WriteString(footerStyle.Render("  [↑/↓] move  [o] sort  [s] back") + "\n")
 
 	return b.String()
 }
@@ -722,10 +1079,27 @@ func (m model) renderCalendarContent() string {
 	lastDay := firstDay.AddDate(0, 1, -1).Day()
 	narrow := m.narrow()
 
-	var monthTotal TokenStats
+	// reasoning column, only when some day this month has reasoning tokens
+	showReasoning := false
+	for _, models := range m.calendarData {
+		showReasoning = showReasoning || models.Sum().Reasoning > 0
+	}
+	rsnCol := func(n int) string {
+		switch {
+		case !showReasoning:
+			return ""
+		case narrow:
+			return fmt.Sprintf(" %6s", formatTokenCount(n))
+		default:
+			return fmt.Sprintf("  %7s rsn", formatTokenCount(n))
+		}
+	}
+
+	monthTotal := make(ModelTokenStats)
 
 	for day := 1; day <= lastDay; day++ {
-		stats, ok := m.calendarData[day]
+		models, ok := m.calendarData[day]
+		stats := models.Sum()
 		if !ok || stats.Total() == 0 {
 			continue
 		}
@@ -739,12 +1113,13 @@ func (m model) renderCalendarContent() string {
 		totalIn := stats.InputTokens + stats.CacheCreation + stats.CacheRead
 		inStr := formatTokenCount(totalIn)
 		outStr := formatTokenCount(stats.OutputTokens)
+		costStr := m.costCell(models)
 
 		var line string
 		if narrow {
-			line = fmt.Sprintf("  %s %s %6s %6s", dayStr, weekday, inStr, outStr)
+			line = fmt.Sprintf("  %s %s %6s %6s%s %6s", dayStr, weekday, inStr, outStr, rsnCol(stats.Reasoning), costStr)
 		} else {
-			line = fmt.Sprintf("  %s  %s  %7s in  %7s out", dayStr, weekday, inStr, outStr)
+			line = fmt.Sprintf("  %s  %s  %7s in  %7s out%s  %7s", dayStr, weekday, inStr, outStr, rsnCol(stats.Reasoning), costStr)
 		}
 
 		if isToday {
@@ -753,22 +1128,21 @@ func (m model) renderCalendarContent() string {
 			b.WriteString(valStyle.Render(line) + "\n")
 		}
 
-		monthTotal.InputTokens += stats.InputTokens
-		monthTotal.OutputTokens += stats.OutputTokens
-		monthTotal.CacheCreation += stats.CacheCreation
-		monthTotal.CacheRead += stats.CacheRead
+		monthTotal.Merge(models)
 	}
 
-	if monthTotal.Total() > 0 {
-		totalIn := monthTotal.InputTokens + monthTotal.CacheCreation + monthTotal.CacheRead
+	if total := monthTotal.Sum(); total.Total() > 0 {
+		totalIn := total.InputTokens + total.CacheCreation + total.CacheRead
 		inStr := formatTokenCount(totalIn)
-		outStr := formatTokenCount(monthTotal.OutputTokens)
+		outStr := formatTokenCount(total.OutputTokens)
+		costStr := m.costCell(monthTotal)
+		rsn := rsnCol(total.Reasoning)
 		if narrow {
-			b.WriteString(dimStyle.Render("  ──────────────────") + "\n")
-			b.WriteString(valStyle.Render(fmt.Sprintf("       %6s %6s", inStr, outStr)) + "\n")
+			b.WriteString(dimStyle.Render("  ─────────────────────────"+strings.Repeat("─", len(rsn))) + "\n")
+			b.WriteString(valStyle.Render(fmt.Sprintf("         %6s %6s%s %6s", inStr, outStr, rsn, costStr)) + "\n")
 		} else {
-			b.WriteString(dimStyle.Render("  ──────────────────────────────") + "\n")
-			b.WriteString(valStyle.Render(fmt.Sprintf("              %7s in  %7s out", inStr, outStr)) + "\n")
+			b.WriteString(dimStyle.Render("  ───────────────────────────────────────"+strings.Repeat("─", len(rsn))) + "\n")
+			b.WriteString(valStyle.Render(fmt.Sprintf("              %7s in  %7s out%s  %7s", inStr, outStr, rsn, costStr)) + "\n")
 		}
 	}
 
@@ -777,12 +1151,7 @@ func (m model) renderCalendarContent() string {
 	return b.String()
 }
 
-func formatReset(iso string) string {
-	t, err := time.Parse(time.RFC3339, iso)
-	if err != nil {
-		return iso
-	}
-
+func formatReset(t time.Time) string {
 	until := time.Until(t)
 	if until <= 0 {
 		return "resetting..."
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchInterval is how often changed session files are passed on while
+// an agent keeps writing: changes are batched, not debounced, so counters
+// keep ticking up during a long turn.
+const watchInterval = time.Second
+
+// usageWatcher watches the session directories of every watchProvider
+// and reports the files written to, in batches.
+type usageWatcher struct {
+	fs      *fsnotify.Watcher
+	changes chan []string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// newUsageWatcher starts watching the session directories of ps,
+// including every subdirectory. Directories that can't be watched are
+// skipped; the periodic refresh still covers them.
+func newUsageWatcher(ps []Provider) (*usageWatcher, error) {
+	fs, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &usageWatcher{
+		fs:      fs,
+		changes: make(chan []string),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	for _, p := range ps {
+		if wp, ok := p.(watchProvider); ok {
+			for _, dir := range wp.WatchDirs() {
+				w.addTree(dir, nil)
+			}
+		}
+	}
+	go w.run()
+	return w, nil
+}
+
+// addTree watches dir and its subdirectories. The files already in them
+// are added to found, if not nil, since a directory created after the
+// last event may have been written to before it was watched.
+func (w *usageWatcher) addTree(dir string, found map[string]bool) {
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			w.fs.Add(path)
+		} else if found != nil {
+			found[path] = true
+		}
+		return nil
+	})
+}
+
+// run collects file events until the watcher is closed, sending the
+// changed paths on w.changes at most once per watchInterval.
+func (w *usageWatcher) run() {
+	defer w.fs.Close()
+	changed := make(map[string]bool)
+	var flush <-chan time.Time
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case ev, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			if ev.Op == fsnotify.Chmod {
+				continue
+			}
+			if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+				if ev.Has(fsnotify.Create) {
+					w.addTree(ev.Name, changed)
+				}
+			} else {
+				changed[ev.Name] = true
+			}
+			if flush == nil {
+				flush = time.After(watchInterval)
+			}
+		case <-flush:
+			flush = nil
+			if len(changed) == 0 {
+				continue
+			}
+			paths := slices.Sorted(maps.Keys(changed))
+			clear(changed)
+			select {
+			case w.changes <- paths:
+			case <-w.ctx.Done():
+				return
+			}
+		case _, ok := <-w.fs.Errors:
+			// e.g. an event queue overflow; the periodic refresh catches up
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// Wait returns a command that delivers the next batch of changed files.
+func (w *usageWatcher) Wait() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case paths := <-w.changes:
+			return filesChangedMsg{paths: paths}
+		case <-w.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Close stops the watcher.
+func (w *usageWatcher) Close() {
+	w.cancel()
+}